@@ -0,0 +1,427 @@
+package brc20
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/btcutil/psbt"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/mempool"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+)
+
+const (
+	BidSigHashType = txscript.SigHashAll | txscript.SigHashAnyOneCanPay
+
+	inscriptionTagParent = 3
+)
+
+var (
+	ErrInvalidBid           = errors.New("invalid bid")
+	ErrInvalidInscriptionId = errors.New("invalid inscription id")
+	ErrInscriptionNotFound  = errors.New("inscription not found")
+	ErrParentNotAllowed     = errors.New("inscription parent not allowed")
+	ErrParentNotSpent       = errors.New("inscription parent not spent by the reveal tx")
+)
+
+type BidRequest struct {
+	FundingInputs  []*TxInput `json:"fundingInputs"`
+	Price          int64      `json:"price"`
+	PaymentAddress string     `json:"paymentAddress"`
+	ReceiveAddress string     `json:"receiveAddress"`
+	// ReceiveValue is the value of the inscription output, default 546. The seller
+	// input must hold exactly as much, the bid has no output for any excess.
+	ReceiveValue  int64   `json:"receiveValue"`
+	ChangeAddress string  `json:"changeAddress"`
+	FeeRate       FeeRate `json:"feeRate"`
+	// FeeGuard bounds FeeRate, default DefaultFeeGuard. The fee of the completed tx
+	// is checked by AcceptBid.
+	FeeGuard *FeeGuard `json:"feeGuard"`
+}
+
+// BidInscription identifies the inscription a seller sells into a bid.
+type BidInscription struct {
+	// Id is the inscription id, "<reveal txid>i<index>"
+	Id string `json:"id"`
+	// RevealTx is the hex of the reveal tx of the inscription
+	RevealTx string `json:"revealTx"`
+	// Location is the current "<txid>:<vout>:<offset>" of the inscription, as
+	// reported by an ord indexer
+	Location string `json:"location"`
+	// ParentLocations maps each parent inscription id to its "<txid>:<vout>:<offset>"
+	// when the reveal tx was made, as reported by an ord indexer. Like ord, a parent
+	// only counts when the reveal tx spends it.
+	ParentLocations map[string]string `json:"parentLocations"`
+}
+
+type AcceptBidRequest struct {
+	// Bid is the base64 PSBT returned by CreateBid
+	Bid            string          `json:"bid"`
+	SellerInput    *TxInput        `json:"sellerInput"`
+	Inscription    *BidInscription `json:"inscription"`
+	AllowedParents []string        `json:"allowedParents"`
+	// FeeGuard bounds the fee of the completed tx, default DefaultFeeGuard
+	FeeGuard *FeeGuard `json:"feeGuard"`
+}
+
+// CreateBid builds a bid PSBT: the buyer's funding inputs signed with
+// SIGHASH_ALL|ANYONECANPAY against the inscription output to ReceiveAddress, the
+// payment output and the change. A seller completes it with AcceptBid by adding the
+// inscription input in front of the funding inputs. SIGHASH_ALL fixes the outputs, so
+// the payment address is chosen by the buyer, e.g. a marketplace settlement address
+// for a collection bid. The fee assumes a taproot seller input.
+func CreateBid(request *BidRequest, network *chaincfg.Params) (string, error) {
+	if err := request.FeeGuard.orDefault().checkFeeRate(request.FeeRate); err != nil {
+		return "", err
+	}
+	receiveValue := DefaultRevealOutValue
+	if request.ReceiveValue > 0 {
+		receiveValue = request.ReceiveValue
+	}
+	receivePkScript, err := AddrToPkScript(request.ReceiveAddress, network)
+	if err != nil {
+		return "", err
+	}
+	paymentPkScript, err := AddrToPkScript(request.PaymentAddress, network)
+	if err != nil {
+		return "", err
+	}
+	changePkScript, err := AddrToPkScript(request.ChangeAddress, network)
+	if err != nil {
+		return "", err
+	}
+
+	prevOuts := txscript.NewMultiPrevOutFetcher(nil)
+	var outPoints []*wire.OutPoint
+	var nSequences []uint32
+	// the seller input is expected to be a taproot one
+	inPkScripts := [][]byte{make([]byte, 34)}
+	inPkScripts[0][0] = txscript.OP_1
+	inPkScripts[0][1] = txscript.OP_DATA_32
+	totalIn := receiveValue
+	for _, in := range request.FundingInputs {
+		if in.IsPadding {
+			return "", fmt.Errorf("%w: %s:%d", ErrPaddingUtxo, in.TxId, in.VOut)
+		}
+		txHash, err := chainhash.NewHashFromStr(in.TxId)
+		if err != nil {
+			return "", err
+		}
+		outPoint := wire.NewOutPoint(txHash, in.VOut)
+		pkScript, err := AddrToPkScript(in.Address, network)
+		if err != nil {
+			return "", err
+		}
+		outPoints = append(outPoints, outPoint)
+		inPkScripts = append(inPkScripts, pkScript)
+		nSequences = append(nSequences, wire.MaxTxInSequenceNum)
+		prevOuts.AddPrevOut(*outPoint, wire.NewTxOut(in.Amount, pkScript))
+		totalIn += in.Amount
+	}
+
+	outputs := []*wire.TxOut{wire.NewTxOut(receiveValue, receivePkScript), wire.NewTxOut(request.Price, paymentPkScript)}
+	totalOut := receiveValue + request.Price
+	changeOut := wire.NewTxOut(0, changePkScript)
+	weight, err := estimateTxWeight(inPkScripts, outputs)
+	if err != nil {
+		return "", err
+	}
+	change := totalIn - totalOut - request.FeeRate.FeeForWeight(weight+int64(changeOut.SerializeSize())*4)
+	changeOut.Value = change
+	if change > 0 && !mempool.IsDust(changeOut, mempool.DefaultMinRelayTxFee) {
+		outputs = append(outputs, changeOut)
+	} else if totalIn-totalOut < request.FeeRate.FeeForWeight(weight) {
+		return "", ErrInsufficientBalance
+	}
+
+	bp, err := psbt.New(outPoints, outputs, txVersion, nLockTime, nSequences)
+	if err != nil {
+		return "", err
+	}
+	updater, err := psbt.NewUpdater(bp)
+	if err != nil {
+		return "", err
+	}
+	for i, in := range request.FundingInputs {
+		if err = signInput(updater, i, in, prevOuts, BidSigHashType, network); err != nil {
+			return "", err
+		}
+	}
+	return bp.B64Encode()
+}
+
+// AcceptBid checks that the seller input holds an inscription, at offset 0, whose
+// reveal tx names one of AllowedParents as its parent and spends it, adds the seller
+// input in front of the bid's funding inputs, signs it and returns the final
+// transaction. The parent tag alone proves nothing, anyone can write it; the
+// locations of the inscription and its parents are trusted to the ord indexer.
+func AcceptBid(request *AcceptBidRequest, network *chaincfg.Params) (*TransferResult, error) {
+	bid, err := psbt.NewFromRawBytes(strings.NewReader(request.Bid), true)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidBid, err)
+	}
+	if len(bid.UnsignedTx.TxOut) < 2 {
+		return nil, fmt.Errorf("%w: expected inscription and payment outputs", ErrInvalidBid)
+	}
+	prevOuts := txscript.NewMultiPrevOutFetcher(nil)
+	totalIn := int64(0)
+	for i, pInput := range bid.Inputs {
+		if pInput.SighashType != BidSigHashType {
+			return nil, fmt.Errorf("%w: input %d sighash type %v", ErrInvalidBid, i, pInput.SighashType)
+		}
+		outPoint := bid.UnsignedTx.TxIn[i].PreviousOutPoint
+		prevOut, err := pInputPrevOut(&bid.Inputs[i], outPoint)
+		if err != nil {
+			return nil, fmt.Errorf("%w: input %d %v", ErrInvalidBid, i, err)
+		}
+		if prevOut == nil {
+			return nil, fmt.Errorf("%w: input %d utxo missing", ErrInvalidBid, i)
+		}
+		prevOuts.AddPrevOut(outPoint, prevOut)
+		totalIn += prevOut.Value
+	}
+
+	seller := request.SellerInput
+	if seller.IsPadding {
+		return nil, fmt.Errorf("%w: %s:%d", ErrPaddingUtxo, seller.TxId, seller.VOut)
+	}
+	sellerTxHash, err := chainhash.NewHashFromStr(seller.TxId)
+	if err != nil {
+		return nil, err
+	}
+	sellerOutPoint := wire.NewOutPoint(sellerTxHash, seller.VOut)
+	if err = checkBidInscription(request.Inscription, sellerOutPoint, request.AllowedParents); err != nil {
+		return nil, err
+	}
+	receiveValue := bid.UnsignedTx.TxOut[0].Value
+	// the bid fixes every output, anything above receiveValue would go to the miners
+	if seller.Amount != receiveValue {
+		return nil, fmt.Errorf("%w: seller input value %d is not the inscription output value %d", ErrInvalidBid, seller.Amount, receiveValue)
+	}
+	sellerPkScript, err := AddrToPkScript(seller.Address, network)
+	if err != nil {
+		return nil, err
+	}
+	prevOuts.AddPrevOut(*sellerOutPoint, wire.NewTxOut(seller.Amount, sellerPkScript))
+	totalIn += seller.Amount
+
+	outPoints := []*wire.OutPoint{sellerOutPoint}
+	nSequences := []uint32{wire.MaxTxInSequenceNum}
+	for _, in := range bid.UnsignedTx.TxIn {
+		outPoints = append(outPoints, &in.PreviousOutPoint)
+		nSequences = append(nSequences, in.Sequence)
+	}
+	bp, err := psbt.New(outPoints, bid.UnsignedTx.TxOut, bid.UnsignedTx.Version, bid.UnsignedTx.LockTime, nSequences)
+	if err != nil {
+		return nil, err
+	}
+	copy(bp.Inputs[1:], bid.Inputs)
+	updater, err := psbt.NewUpdater(bp)
+	if err != nil {
+		return nil, err
+	}
+	if err = signInput(updater, 0, seller, prevOuts, txscript.SigHashAll, network); err != nil {
+		return nil, err
+	}
+	for i := range bp.Inputs {
+		if err = finalizeInput(bp, i); err != nil {
+			return nil, fmt.Errorf("finalize input %d: %w", i, err)
+		}
+	}
+	tx, err := psbt.Extract(bp)
+	if err != nil {
+		return nil, err
+	}
+	for i := 1; i < len(tx.TxIn); i++ {
+		if err = verifyInput(tx, i, prevOuts); err != nil {
+			return nil, fmt.Errorf("%w: input %d signature: %v", ErrInvalidBid, i-1, err)
+		}
+	}
+	if err = request.FeeGuard.orDefault().checkTx("bid", 0, tx, totalIn); err != nil {
+		return nil, err
+	}
+
+	totalOut, change := int64(0), int64(0)
+	for _, out := range tx.TxOut {
+		totalOut += out.Value
+	}
+	if len(tx.TxOut) > 2 {
+		change = tx.TxOut[2].Value
+	}
+	txHex, err := getTxHex(tx)
+	if err != nil {
+		return nil, err
+	}
+	return &TransferResult{
+		Tx:     txHex,
+		Fee:    totalIn - totalOut,
+		VSize:  mempool.GetTxVirtualSize(btcutil.NewTx(tx)),
+		Change: change,
+	}, nil
+}
+
+func checkBidInscription(inscription *BidInscription, sellerOutPoint *wire.OutPoint, allowedParents []string) error {
+	if inscription == nil {
+		return fmt.Errorf("%w: missing inscription", ErrInvalidBid)
+	}
+	revealTxHash, index, err := parseInscriptionId(inscription.Id)
+	if err != nil {
+		return err
+	}
+	location := strings.Split(inscription.Location, ":")
+	if len(location) != 3 || location[0] != sellerOutPoint.Hash.String() ||
+		location[1] != strconv.FormatUint(uint64(sellerOutPoint.Index), 10) || location[2] != "0" {
+		return fmt.Errorf("%w: inscription location %s is not offset 0 of the seller input %s",
+			ErrInvalidBid, inscription.Location, sellerOutPoint)
+	}
+	revealTx, err := newTxFromHex(inscription.RevealTx)
+	if err != nil {
+		return err
+	}
+	if revealTx.TxHash() != *revealTxHash {
+		return fmt.Errorf("%w: reveal tx %s does not match %s", ErrInvalidBid, revealTx.TxHash(), inscription.Id)
+	}
+	envelopes := inscriptionEnvelopes(revealTx)
+	if int(index) >= len(envelopes) {
+		return fmt.Errorf("%w: %s", ErrInscriptionNotFound, inscription.Id)
+	}
+	allowedFound := false
+	for _, parent := range envelopes[index].parents {
+		for _, allowed := range allowedParents {
+			if parent != allowed {
+				continue
+			}
+			allowedFound = true
+			if spendsLocation(revealTx, inscription.ParentLocations[parent]) {
+				return nil
+			}
+		}
+	}
+	if allowedFound {
+		return fmt.Errorf("%w: %s", ErrParentNotSpent, inscription.Id)
+	}
+	return fmt.Errorf("%w: %s has parents %v", ErrParentNotAllowed, inscription.Id, envelopes[index].parents)
+}
+
+// spendsLocation reports whether tx spends the output of a "<txid>:<vout>:<offset>"
+// location.
+func spendsLocation(tx *wire.MsgTx, location string) bool {
+	parts := strings.Split(location, ":")
+	if len(parts) != 3 {
+		return false
+	}
+	for _, in := range tx.TxIn {
+		if parts[0] == in.PreviousOutPoint.Hash.String() &&
+			parts[1] == strconv.FormatUint(uint64(in.PreviousOutPoint.Index), 10) {
+			return true
+		}
+	}
+	return false
+}
+
+func parseInscriptionId(id string) (*chainhash.Hash, uint32, error) {
+	i := strings.LastIndex(id, "i")
+	if i != chainhash.MaxHashStringSize {
+		return nil, 0, fmt.Errorf("%w: %s", ErrInvalidInscriptionId, id)
+	}
+	hash, err := chainhash.NewHashFromStr(id[:i])
+	if err != nil {
+		return nil, 0, fmt.Errorf("%w: %s", ErrInvalidInscriptionId, id)
+	}
+	index, err := strconv.ParseUint(id[i+1:], 10, 32)
+	if err != nil {
+		return nil, 0, fmt.Errorf("%w: %s", ErrInvalidInscriptionId, id)
+	}
+	return hash, uint32(index), nil
+}
+
+type inscriptionEnvelope struct {
+	parents []string
+}
+
+// inscriptionEnvelopes returns the ord envelopes in the tapscripts of tx, in
+// inscription index order.
+func inscriptionEnvelopes(tx *wire.MsgTx) []*inscriptionEnvelope {
+	var envelopes []*inscriptionEnvelope
+	for _, in := range tx.TxIn {
+		witness := in.Witness
+		if len(witness) >= 2 && len(witness[len(witness)-1]) > 0 && witness[len(witness)-1][0] == txscript.TaprootAnnexTag {
+			witness = witness[:len(witness)-1]
+		}
+		if len(witness) < 2 {
+			continue
+		}
+		envelopes = append(envelopes, parseEnvelopes(witness[len(witness)-2])...)
+	}
+	return envelopes
+}
+
+func parseEnvelopes(tapscript []byte) []*inscriptionEnvelope {
+	var envelopes []*inscriptionEnvelope
+	tokenizer := txscript.MakeScriptTokenizer(0, tapscript)
+	// the last three opcodes, to find OP_FALSE OP_IF "ord". It starts out with
+	// OP_NOP, as zero bytes would read as an OP_FALSE before the script.
+	window := [3]byte{txscript.OP_NOP, txscript.OP_NOP, txscript.OP_NOP}
+	var windowData []byte
+	for tokenizer.Next() {
+		window[0], window[1], window[2] = window[1], window[2], tokenizer.Opcode()
+		windowData = tokenizer.Data()
+		if window[0] != txscript.OP_FALSE || window[1] != txscript.OP_IF || !bytes.Equal(windowData, []byte("ord")) {
+			continue
+		}
+		envelope := &inscriptionEnvelope{}
+		for tokenizer.Next() && tokenizer.Opcode() != txscript.OP_ENDIF {
+			tag, ok := pushValue(tokenizer.Opcode(), tokenizer.Data())
+			if !ok || len(tag) == 0 || (len(tag) == 1 && tag[0] == 0) {
+				// the body, or an invalid envelope
+				break
+			}
+			if !tokenizer.Next() {
+				break
+			}
+			value, ok := pushValue(tokenizer.Opcode(), tokenizer.Data())
+			if !ok {
+				break
+			}
+			if len(tag) == 1 && tag[0] == inscriptionTagParent {
+				if parent, ok := inscriptionIdFromValue(value); ok {
+					envelope.parents = append(envelope.parents, parent)
+				}
+			}
+		}
+		envelopes = append(envelopes, envelope)
+	}
+	return envelopes
+}
+
+func pushValue(opcode byte, data []byte) ([]byte, bool) {
+	switch {
+	case opcode == txscript.OP_0:
+		return []byte{}, true
+	case opcode <= txscript.OP_PUSHDATA4:
+		return data, true
+	case opcode >= txscript.OP_1 && opcode <= txscript.OP_16:
+		return []byte{opcode - txscript.OP_1 + 1}, true
+	}
+	return nil, false
+}
+
+// inscriptionIdFromValue decodes an inscription id tag value: the txid followed by
+// the little-endian index without trailing zero bytes.
+func inscriptionIdFromValue(value []byte) (string, bool) {
+	if len(value) < chainhash.HashSize || len(value) > chainhash.HashSize+4 {
+		return "", false
+	}
+	hash, _ := chainhash.NewHash(value[:chainhash.HashSize])
+	index := uint32(0)
+	for i, b := range value[chainhash.HashSize:] {
+		index |= uint32(b) << (8 * i)
+	}
+	return fmt.Sprintf("%si%d", hash, index), true
+}
@@ -0,0 +1,206 @@
+package brc20
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/btcsuite/btcd/btcutil/psbt"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+)
+
+// newTestRevealTx returns a reveal tx whose single envelope names parentId as parent.
+func newTestRevealTx(t *testing.T, parentHash *chainhash.Hash, parentIndex byte) *wire.MsgTx {
+	parent := append(parentHash.CloneBytes(), parentIndex)
+	if parentIndex == 0 {
+		parent = parent[:chainhash.HashSize]
+	}
+	tapscript, err := txscript.NewScriptBuilder().
+		AddData(make([]byte, 32)).AddOp(txscript.OP_CHECKSIG).
+		AddOp(txscript.OP_FALSE).AddOp(txscript.OP_IF).
+		AddData([]byte("ord")).
+		AddOp(txscript.OP_1).AddData([]byte("text/plain;charset=utf-8")).
+		AddOp(txscript.OP_3).AddData(parent).
+		AddOp(txscript.OP_0).AddData([]byte("child")).
+		AddOp(txscript.OP_ENDIF).
+		Script()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx := wire.NewMsgTx(txVersion)
+	tx.AddTxIn(wire.NewTxIn(&wire.OutPoint{Hash: *parentHash, Index: 0}, nil, nil))
+	tx.AddTxIn(wire.NewTxIn(&wire.OutPoint{Hash: chainhash.Hash{1}, Index: 0},
+		nil, wire.TxWitness{make([]byte, 64), tapscript, make([]byte, 33)}))
+	sellerPkScript, _ := AddrToPkScript("tb1pklh8lqax5l7m2ycypptv2emc4gata2dy28svnwcp9u32wlkenvsspcvhsr", &chaincfg.TestNet3Params)
+	tx.AddTxOut(wire.NewTxOut(546, sellerPkScript))
+	tx.AddTxOut(wire.NewTxOut(546, sellerPkScript))
+	return tx
+}
+
+func TestParseInscriptionEnvelopes(t *testing.T) {
+	parentHash, _ := chainhash.NewHashFromStr("25b9d08a26c8d47795301dd47a861cff0459d14f27fbd41cffaca17d9aa20f87")
+	for _, index := range []byte{0, 1} {
+		tx := newTestRevealTx(t, parentHash, index)
+		envelopes := inscriptionEnvelopes(tx)
+		if len(envelopes) != 1 {
+			t.Fatalf("got %d envelopes", len(envelopes))
+		}
+		want := fmt.Sprintf("%si%d", parentHash, index)
+		if len(envelopes[0].parents) != 1 || envelopes[0].parents[0] != want {
+			t.Fatalf("parents %v, want %s", envelopes[0].parents, want)
+		}
+	}
+
+	// OP_IF "ord" at the start of the script has no OP_FALSE before it
+	tapscript, _ := txscript.NewScriptBuilder().
+		AddOp(txscript.OP_IF).AddData([]byte("ord")).
+		AddOp(txscript.OP_3).AddData(parentHash.CloneBytes()).
+		AddOp(txscript.OP_ENDIF).
+		Script()
+	if envelopes := parseEnvelopes(tapscript); len(envelopes) != 0 {
+		t.Fatalf("got %d envelopes without OP_FALSE", len(envelopes))
+	}
+
+	if _, _, err := parseInscriptionId("25b9d08a26c8d47795301dd47a861cff0459d14f27fbd41cffaca17d9aa20f87"); !errors.Is(err, ErrInvalidInscriptionId) {
+		t.Fatalf("expected ErrInvalidInscriptionId, got %v", err)
+	}
+}
+
+func TestBid(t *testing.T) {
+	network := &chaincfg.TestNet3Params
+
+	request := &BidRequest{
+		FundingInputs: []*TxInput{
+			{
+				TxId:       "25b9d08a26c8d47795301dd47a861cff0459d14f27fbd41cffaca17d9aa20f87",
+				VOut:       uint32(1),
+				Amount:     int64(249352),
+				Address:    "tb1qtsq9c4fje6qsmheql8gajwtrrdrs38kdzeersc",
+				PrivateKey: "cPnvkvUYyHcSSS26iD1dkrJdV7k1RoUqJLhn3CYxpo398PdLVE22",
+			},
+		},
+		Price:          100000,
+		PaymentAddress: "2NF33rckfiQTiE5Guk5ufUdwms8PgmtnEdc",
+		ReceiveAddress: "tb1qtsq9c4fje6qsmheql8gajwtrrdrs38kdzeersc",
+		ChangeAddress:  "tb1qtsq9c4fje6qsmheql8gajwtrrdrs38kdzeersc",
+		FeeRate:        3,
+	}
+	bid, err := CreateBid(request, network)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for feeRate, want := range map[FeeRate]error{0: ErrInvalidFeeRate, -1: ErrInvalidFeeRate, MaxFeeRate + 1: ErrAbsurdFeeRate} {
+		badRate := *request
+		badRate.FeeRate = feeRate
+		if _, err := CreateBid(&badRate, network); !errors.Is(err, want) {
+			t.Fatalf("fee rate %v: expected %v, got %v", feeRate, want, err)
+		}
+	}
+	bp, err := psbt.NewFromRawBytes(strings.NewReader(bid), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(bp.UnsignedTx.TxOut) != 3 || bp.UnsignedTx.TxOut[0].Value != DefaultRevealOutValue || bp.UnsignedTx.TxOut[1].Value != 100000 {
+		t.Fatal("unexpected bid layout")
+	}
+	if bp.Inputs[0].SighashType != BidSigHashType || len(bp.Inputs[0].PartialSigs) != 1 {
+		t.Fatal("bid input must be signed with ALL|ANYONECANPAY")
+	}
+
+	parentHash, _ := chainhash.NewHashFromStr("d1696c10046ec8b2d938924f1923f1f2e1588095fbf3ea0f8cd640b51da51ba2")
+	revealTx := newTestRevealTx(t, parentHash, 0)
+	revealTxHex, _ := getTxHex(revealTx)
+	revealTxId := revealTx.TxHash().String()
+	acceptRequest := func() *AcceptBidRequest {
+		return &AcceptBidRequest{
+			Bid: bid,
+			SellerInput: &TxInput{
+				TxId:       revealTxId,
+				VOut:       uint32(1),
+				Amount:     int64(546),
+				Address:    "tb1pklh8lqax5l7m2ycypptv2emc4gata2dy28svnwcp9u32wlkenvsspcvhsr",
+				PrivateKey: "cPnvkvUYyHcSSS26iD1dkrJdV7k1RoUqJLhn3CYxpo398PdLVE22",
+			},
+			Inscription: &BidInscription{
+				Id:       revealTxId + "i0",
+				RevealTx: revealTxHex,
+				Location: revealTxId + ":1:0",
+				ParentLocations: map[string]string{
+					parentHash.String() + "i0": parentHash.String() + ":0:0",
+				},
+			},
+			AllowedParents: []string{parentHash.String() + "i0"},
+		}
+	}
+
+	result, err := AcceptBid(acceptRequest(), network)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx, err := newTxFromHex(result.Tx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tx.TxIn) != 2 || tx.TxIn[0].PreviousOutPoint.Hash.String() != revealTxId {
+		t.Fatal("seller input must be at index 0")
+	}
+	if result.Fee < request.FeeRate.FeeForVSize(result.VSize) {
+		t.Fatalf("fee %d below %v sat/vB for %d vB", result.Fee, request.FeeRate, result.VSize)
+	}
+	prevOuts := txscript.NewMultiPrevOutFetcher(nil)
+	for i, in := range []*TxInput{acceptRequest().SellerInput, request.FundingInputs[0]} {
+		pkScript, _ := AddrToPkScript(in.Address, network)
+		prevOuts.AddPrevOut(tx.TxIn[i].PreviousOutPoint, wire.NewTxOut(in.Amount, pkScript))
+	}
+	for i := range tx.TxIn {
+		if err := verifyInput(tx, i, prevOuts); err != nil {
+			t.Fatalf("input %d: %v", i, err)
+		}
+	}
+
+	notAllowed := acceptRequest()
+	notAllowed.AllowedParents = []string{parentHash.String() + "i1"}
+	if _, err := AcceptBid(notAllowed, network); !errors.Is(err, ErrParentNotAllowed) {
+		t.Fatalf("expected ErrParentNotAllowed, got %v", err)
+	}
+	// a parent tag without the parent spent in the reveal
+	for _, location := range []string{"", parentHash.String() + ":1:0"} {
+		notSpent := acceptRequest()
+		notSpent.Inscription.ParentLocations[parentHash.String()+"i0"] = location
+		if _, err := AcceptBid(notSpent, network); !errors.Is(err, ErrParentNotSpent) {
+			t.Fatalf("%q: expected ErrParentNotSpent, got %v", location, err)
+		}
+	}
+	wrongLocation := acceptRequest()
+	wrongLocation.Inscription.Location = revealTxId + ":0:0"
+	if _, err := AcceptBid(wrongLocation, network); !errors.Is(err, ErrInvalidBid) {
+		t.Fatalf("expected ErrInvalidBid, got %v", err)
+	}
+	oversized := acceptRequest()
+	oversized.SellerInput.Amount = 10000
+	if _, err := AcceptBid(oversized, network); !errors.Is(err, ErrInvalidBid) {
+		t.Fatalf("expected ErrInvalidBid, got %v", err)
+	}
+	// a non-witness utxo must be the tx the bid input spends
+	forgedBid, _ := psbt.NewFromRawBytes(strings.NewReader(bid), true)
+	forged := wire.NewMsgTx(txVersion)
+	forged.AddTxIn(wire.NewTxIn(&wire.OutPoint{Hash: chainhash.Hash{9}}, nil, nil))
+	forged.AddTxOut(wire.NewTxOut(1, forgedBid.Inputs[0].WitnessUtxo.PkScript))
+	forged.AddTxOut(forgedBid.Inputs[0].WitnessUtxo)
+	forgedBid.Inputs[0].WitnessUtxo, forgedBid.Inputs[0].NonWitnessUtxo = nil, forged
+	forgedRequest := acceptRequest()
+	forgedRequest.Bid, _ = forgedBid.B64Encode()
+	if _, err := AcceptBid(forgedRequest, network); !errors.Is(err, ErrInvalidBid) ||
+		!strings.Contains(err.Error(), ErrUtxoMismatch.Error()) {
+		t.Fatalf("expected a bid utxo mismatch, got %v", err)
+	}
+	missing := acceptRequest()
+	missing.Inscription.Id = revealTxId + "i1"
+	if _, err := AcceptBid(missing, network); !errors.Is(err, ErrInscriptionNotFound) {
+		t.Fatalf("expected ErrInscriptionNotFound, got %v", err)
+	}
+}
@@ -0,0 +1,154 @@
+package brc20
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/mempool"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+)
+
+const (
+	// LitecoinDefaultRevealOutValue is the ord-litecoin default postage.
+	LitecoinDefaultRevealOutValue = int64(10000)
+)
+
+var (
+	ErrDustOutput        = errors.New("output value below dust limit")
+	ErrUnsupportedOption = errors.New("option not supported by chain backend")
+)
+
+// ChainBackend builds inscription transactions for one chain. Inscribe dispatches to
+// the backend registered for its network, and to a bitcoin taproot backend otherwise.
+type ChainBackend interface {
+	Params() *chaincfg.Params
+	IsDust(txOut *wire.TxOut) bool
+	FeeRateUnit() string
+	Inscribe(request *InscriptionRequest) (*InscribeTxs, error)
+}
+
+// TaprootBackend inscribes with ord tapscript envelopes in a commit/reveal pair.
+// It serves bitcoin and litecoin, which has taproot but no MWEB support here, so
+// neither the inputs nor the outputs may be MWEB ones.
+type TaprootBackend struct {
+	Network        *chaincfg.Params
+	RevealOutValue int64
+}
+
+func (backend *TaprootBackend) Params() *chaincfg.Params {
+	return backend.Network
+}
+
+func (backend *TaprootBackend) IsDust(txOut *wire.TxOut) bool {
+	return mempool.IsDust(txOut, mempool.DefaultMinRelayTxFee)
+}
+
+func (backend *TaprootBackend) FeeRateUnit() string {
+	return "sat/vB"
+}
+
+func (backend *TaprootBackend) Inscribe(request *InscriptionRequest) (*InscribeTxs, error) {
+	if request.RevealOutValue <= 0 && backend.RevealOutValue > 0 {
+		backendRequest := *request
+		backendRequest.RevealOutValue = backend.RevealOutValue
+		request = &backendRequest
+	}
+	return inscribeTaproot(backend, request)
+}
+
+var LitecoinMainNetParams = newAltcoinParams(chaincfg.MainNetParams, "litecoin", 0xdbb6c0fb, "9333", "ltc",
+	0x30, 0x32, 0xb0, [4]byte{0x01, 0x9d, 0x9c, 0xfe}, [4]byte{0x01, 0x9d, 0xa4, 0x62}, 2,
+	"12a765e31ffd4059bada1e25190f6e98c99d9714d334efa41a195a7e7e04bfe2")
+
+var LitecoinTestNetParams = newAltcoinParams(chaincfg.TestNet3Params, "litecoin-testnet", 0xf1c8d2fd, "19335", "tltc",
+	0x6f, 0x3a, 0xef, chaincfg.TestNet3Params.HDPrivateKeyID, chaincfg.TestNet3Params.HDPublicKeyID, 1,
+	"4966625a4b2851d9fdee139e56211a0d88575f59ed816ff5e6a63deb4e3e29a0")
+
+var chainBackendRegistry = struct {
+	sync.RWMutex
+	byNet map[wire.BitcoinNet]ChainBackend
+}{
+	byNet: make(map[wire.BitcoinNet]ChainBackend),
+}
+
+func init() {
+	for _, backend := range []ChainBackend{
+		&TaprootBackend{Network: LitecoinMainNetParams, RevealOutValue: LitecoinDefaultRevealOutValue},
+		&TaprootBackend{Network: LitecoinTestNetParams, RevealOutValue: LitecoinDefaultRevealOutValue},
+		&DogecoinBackend{Network: DogecoinMainNetParams},
+		&DogecoinBackend{Network: DogecoinTestNetParams},
+	} {
+		RegisterChainBackend(backend)
+	}
+}
+
+// RegisterChainBackend makes Inscribe use backend for backend.Params().
+func RegisterChainBackend(backend ChainBackend) {
+	chainBackendRegistry.Lock()
+	defer chainBackendRegistry.Unlock()
+
+	chainBackendRegistry.byNet[backend.Params().Net] = backend
+}
+
+func ChainBackendFor(network *chaincfg.Params) ChainBackend {
+	chainBackendRegistry.RLock()
+	defer chainBackendRegistry.RUnlock()
+
+	if backend, ok := chainBackendRegistry.byNet[network.Net]; ok {
+		return backend
+	}
+	return &TaprootBackend{Network: network}
+}
+
+func checkDust(backend ChainBackend, txType string, index int, tx *wire.MsgTx) error {
+	return checkOutputsDust(backend, txType, index, 0, tx.TxOut)
+}
+
+// checkOutputsDust checks outs, which start at output firstOutput of the tx.
+// OP_RETURN outputs are never dust.
+func checkOutputsDust(backend ChainBackend, txType string, index, firstOutput int, outs []*wire.TxOut) error {
+	for i, out := range outs {
+		if txscript.IsUnspendable(out.PkScript) {
+			continue
+		}
+		if backend.IsDust(out) {
+			return fmt.Errorf("%w: %s tx(index %d) output %d value %d", ErrDustOutput, txType, index, firstOutput+i, out.Value)
+		}
+	}
+	return nil
+}
+
+func newAltcoinParams(base chaincfg.Params, name string, net uint32, port, hrp string,
+	pubKeyHashAddrID, scriptHashAddrID, privateKeyID byte, hdPrivateKeyID, hdPublicKeyID [4]byte, hdCoinType uint32,
+	genesisHash string) *chaincfg.Params {
+	params := base
+	params.Name = name
+	params.Net = wire.BitcoinNet(net)
+	params.DefaultPort = port
+	params.DNSSeeds = nil
+	params.GenesisBlock = nil
+	params.GenesisHash = mustHashFromStr(genesisHash)
+	params.Checkpoints = nil
+	params.Bech32HRPSegwit = hrp
+	params.PubKeyHashAddrID = pubKeyHashAddrID
+	params.ScriptHashAddrID = scriptHashAddrID
+	params.PrivateKeyID = privateKeyID
+	params.WitnessPubKeyHashAddrID = 0
+	params.WitnessScriptHashAddrID = 0
+	params.HDPrivateKeyID = hdPrivateKeyID
+	params.HDPublicKeyID = hdPublicKeyID
+	params.HDCoinType = hdCoinType
+	return &params
+}
+
+func mustHashFromStr(hexStr string) *chainhash.Hash {
+	hash, err := chainhash.NewHashFromStr(hexStr)
+	if err != nil {
+		panic(err)
+	}
+	return hash
+}
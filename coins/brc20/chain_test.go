@@ -0,0 +1,64 @@
+package brc20
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/btcsuite/btcd/btcec/v2/schnorr"
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/txscript"
+)
+
+func TestChainBackendFor(t *testing.T) {
+	if _, ok := ChainBackendFor(&chaincfg.TestNet3Params).(*TaprootBackend); !ok {
+		t.Fatal("expected the taproot backend for bitcoin")
+	}
+	if _, ok := ChainBackendFor(LitecoinMainNetParams).(*TaprootBackend); !ok {
+		t.Fatal("expected the taproot backend for litecoin")
+	}
+	if _, ok := ChainBackendFor(DogecoinMainNetParams).(*DogecoinBackend); !ok {
+		t.Fatal("expected the dogecoin backend for dogecoin")
+	}
+}
+
+func TestInscribeLitecoin(t *testing.T) {
+	network := LitecoinTestNetParams
+
+	wif, _ := btcutil.DecodeWIF("cPnvkvUYyHcSSS26iD1dkrJdV7k1RoUqJLhn3CYxpo398PdLVE22")
+	taprootAddress, _ := btcutil.NewAddressTaproot(schnorr.SerializePubKey(txscript.ComputeTaprootKeyNoScript(wif.PrivKey.PubKey())), network)
+	segwitAddress, _ := btcutil.NewAddressWitnessPubKeyHash(btcutil.Hash160(wif.PrivKey.PubKey().SerializeCompressed()), network)
+
+	request := &InscriptionRequest{
+		CommitTxPrevOutputList: []*PrevOutput{{
+			TxId:       "fcd1a1c33df653427e20159a799e6c1ba28421fd168fe353a54508c956fb382e",
+			VOut:       0,
+			Amount:     1000000,
+			Address:    segwitAddress.EncodeAddress(),
+			PrivateKey: "cPnvkvUYyHcSSS26iD1dkrJdV7k1RoUqJLhn3CYxpo398PdLVE22",
+		}},
+		CommitFeeRate: 2,
+		RevealFeeRate: 2,
+		InscriptionDataList: []InscriptionData{{
+			ContentType: "text/plain;charset=utf-8",
+			Body:        []byte(`{"p":"ltc-20","op":"mint","tick":"lite","amt":"1000"}`),
+			RevealAddr:  taprootAddress.EncodeAddress(),
+		}},
+		ChangeAddress: segwitAddress.EncodeAddress(),
+	}
+	txs, err := Inscribe(network, request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	revealTx, _ := newTxFromHex(txs.RevealTxs[0])
+	if revealTx.TxOut[0].Value != LitecoinDefaultRevealOutValue {
+		t.Fatalf("reveal output value %d, want %d", revealTx.TxOut[0].Value, LitecoinDefaultRevealOutValue)
+	}
+	t.Log(taprootAddress.EncodeAddress(), txs.CommitTx)
+
+	// a bitcoin address on litecoin
+	request.ChangeAddress = "tb1qtsq9c4fje6qsmheql8gajwtrrdrs38kdzeersc"
+	if _, err = Inscribe(network, request); !errors.Is(err, ErrNetworkMismatch) {
+		t.Fatalf("expected ErrNetworkMismatch, got %v", err)
+	}
+}
@@ -0,0 +1,109 @@
+package brc20
+
+import (
+	"fmt"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/mempool"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+)
+
+const (
+	// DefaultMinRelayFeeRate is bitcoin core's default -minrelaytxfee in sat/vB.
+	DefaultMinRelayFeeRate = FeeRate(1)
+)
+
+type CPFPRequest struct {
+	ParentTx    string  `json:"parentTx"`
+	ParentFee   int64   `json:"parentFee"`
+	ParentVSize int64   `json:"parentVSize"`
+	VOut        uint32  `json:"vOut"`
+	PrivateKey  string  `json:"privateKey"`
+	ToAddress   string  `json:"toAddress"`
+	FeeRate     FeeRate `json:"feeRate"`
+	// FeeGuard bounds the package fee, default DefaultFeeGuard
+	FeeGuard *FeeGuard `json:"feeGuard"`
+}
+
+type CPFPTx struct {
+	ChildTx    string `json:"childTx"`
+	ChildTxFee int64  `json:"childTxFee"`
+	ChildVSize int64  `json:"childVSize"`
+}
+
+// CPFP spends output VOut of a stuck parent transaction (a commit tx or a Transfer tx)
+// to ToAddress, paying enough fee that parent and child together reach FeeRate.
+// ParentVSize may be left zero, in which case it is computed from ParentTx.
+func CPFP(network *chaincfg.Params, request *CPFPRequest) (*CPFPTx, error) {
+	parentTx, err := newTxFromHex(request.ParentTx)
+	if err != nil {
+		return nil, err
+	}
+	if int(request.VOut) >= len(parentTx.TxOut) {
+		return nil, fmt.Errorf("parent tx has no output %d", request.VOut)
+	}
+	privateKeyWif, err := btcutil.DecodeWIF(request.PrivateKey)
+	if err != nil {
+		return nil, err
+	}
+	toPkScript, err := AddrToPkScript(request.ToAddress, network)
+	if err != nil {
+		return nil, err
+	}
+	parentVSize := request.ParentVSize
+	if parentVSize == 0 {
+		parentVSize = mempool.GetTxVirtualSize(btcutil.NewTx(parentTx))
+	}
+
+	prevOut := parentTx.TxOut[request.VOut]
+	parentTxHash := parentTx.TxHash()
+	outPoint := wire.NewOutPoint(&parentTxHash, request.VOut)
+	prevOutFetcher := txscript.NewMultiPrevOutFetcher(nil)
+	prevOutFetcher.AddPrevOut(*outPoint, prevOut)
+	privateKeys := []*btcec.PrivateKey{privateKeyWif.PrivKey}
+
+	tx := wire.NewMsgTx(DefaultTxVersion)
+	in := wire.NewTxIn(outPoint, nil, nil)
+	in.Sequence = DefaultSequenceNum
+	tx.AddTxIn(in)
+	tx.AddTxOut(wire.NewTxOut(prevOut.Value, toPkScript))
+
+	// the signature length can vary by a byte, so re-sign until the size is stable
+	var childFee, childVSize int64
+	for {
+		if err := sign(tx, privateKeys, prevOutFetcher, nil); err != nil {
+			return nil, err
+		}
+		vsize := mempool.GetTxVirtualSize(btcutil.NewTx(tx))
+		if vsize <= childVSize {
+			break
+		}
+		childVSize = vsize
+		childFee = request.FeeRate.FeeForVSize(parentVSize+childVSize) - request.ParentFee
+		if minFee := DefaultMinRelayFeeRate.FeeForVSize(childVSize); childFee < minFee {
+			childFee = minFee
+		}
+		tx.TxOut[0].Value = prevOut.Value - childFee
+		if tx.TxOut[0].Value <= 0 || mempool.IsDust(tx.TxOut[0], mempool.DefaultMinRelayTxFee) {
+			return nil, ErrInsufficientBalance
+		}
+	}
+
+	// the child pays for its parent, so only the package fee rate is meaningful
+	if err := request.FeeGuard.orDefault().check("cpfp", 0, request.ParentFee+childFee, parentVSize+childVSize, prevOut.Value); err != nil {
+		return nil, err
+	}
+
+	childTx, err := getTxHex(tx)
+	if err != nil {
+		return nil, err
+	}
+	return &CPFPTx{
+		ChildTx:    childTx,
+		ChildTxFee: childFee,
+		ChildVSize: childVSize,
+	}, nil
+}
@@ -0,0 +1,44 @@
+package brc20
+
+import (
+	"testing"
+
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/mempool"
+)
+
+func TestCPFP(t *testing.T) {
+	network := &chaincfg.TestNet3Params
+
+	txs, err := Inscribe(network, newTestInscriptionRequest())
+	if err != nil {
+		t.Fatal(err)
+	}
+	commitTx, _ := newTxFromHex(txs.CommitTx)
+	parentVSize := mempool.GetTxVirtualSize(btcutil.NewTx(commitTx))
+
+	// the change output of the commit tx
+	request := &CPFPRequest{
+		ParentTx:   txs.CommitTx,
+		ParentFee:  txs.CommitTxFee,
+		VOut:       uint32(len(commitTx.TxOut) - 1),
+		PrivateKey: "cPnvkvUYyHcSSS26iD1dkrJdV7k1RoUqJLhn3CYxpo398PdLVE22",
+		ToAddress:  "tb1qtsq9c4fje6qsmheql8gajwtrrdrs38kdzeersc",
+		FeeRate:    20,
+	}
+	child, err := CPFP(network, request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	childTx, _ := newTxFromHex(child.ChildTx)
+	if childTx.TxIn[0].PreviousOutPoint.Hash != commitTx.TxHash() {
+		t.Fatal("child does not spend the parent")
+	}
+	packageFee := txs.CommitTxFee + child.ChildTxFee
+	packageVSize := parentVSize + child.ChildVSize
+	if packageFee < request.FeeRate.FeeForVSize(packageVSize) {
+		t.Fatalf("package fee %d below %v sat/vB for %d vB", packageFee, request.FeeRate, packageVSize)
+	}
+	t.Log(child.ChildTx)
+}
@@ -0,0 +1,431 @@
+package brc20
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+)
+
+const (
+	// DogecoinDustLimit is dogecoin core's hard dust limit of 0.001 DOGE.
+	DogecoinDustLimit             = int64(100000)
+	DogecoinDefaultRevealOutValue = int64(100000)
+	// DogecoinMaxFeeRate is the MaxFeeRate of DefaultDogecoinFeeGuard: 10 DOGE/kB in
+	// koinu/kB, ten thousand times the 0.001 DOGE/kB minimum relay fee.
+	DogecoinMaxFeeRate = FeeRate(1000000000)
+
+	doginalsMaxChunkSize   = 240
+	doginalsMaxPartialSize = 1500
+	maxDERSignatureSize    = 73 // including the sighash type byte
+)
+
+var DogecoinMainNetParams = newAltcoinParams(chaincfg.MainNetParams, "dogecoin", 0xc0c0c0c0, "22556", "",
+	0x1e, 0x16, 0x9e, [4]byte{0x02, 0xfa, 0xc3, 0x98}, [4]byte{0x02, 0xfa, 0xca, 0xfd}, 3,
+	"1a91e3dace36e2be3bf030a65679fe821aa1d6ef92e7c9902eb318182c355691")
+
+var DogecoinTestNetParams = newAltcoinParams(chaincfg.TestNet3Params, "dogecoin-testnet", 0xdcb7c1fc, "44556", "",
+	0x71, 0xc4, 0xf1, chaincfg.TestNet3Params.HDPrivateKeyID, chaincfg.TestNet3Params.HDPublicKeyID, 1,
+	"bb0a78264637406b6360aad926284d544d7049f45189db5664f3c4d07350559e")
+
+// DogecoinBackend inscribes doginals. Dogecoin has no segwit, so the inscription is
+// split into partials that are revealed in the scriptSigs of a chain of P2SH spends:
+// the commit tx funds the first P2SH output of every inscription, each reveal tx
+// spends the previous one and the last reveal pays RevealAddr.
+// Fee rates are in koinu/kB.
+type DogecoinBackend struct {
+	Network *chaincfg.Params
+}
+
+type doginalsPartial struct {
+	script    []byte
+	pushCount int
+	lock      []byte
+	pkScript  []byte
+}
+
+func (backend *DogecoinBackend) Params() *chaincfg.Params {
+	return backend.Network
+}
+
+func (backend *DogecoinBackend) IsDust(txOut *wire.TxOut) bool {
+	return txOut.Value < DogecoinDustLimit
+}
+
+func (backend *DogecoinBackend) FeeRateUnit() string {
+	return "koinu/kB"
+}
+
+// DefaultDogecoinFeeGuard returns the guard of DogecoinBackend given none. Its
+// MaxFeeRate, like every fee rate of the backend, is in koinu/kB.
+func DefaultDogecoinFeeGuard() *FeeGuard {
+	return &FeeGuard{
+		MaxFeeRate: DogecoinMaxFeeRate,
+	}
+}
+
+func (backend *DogecoinBackend) Inscribe(request *InscriptionRequest) (*InscribeTxs, error) {
+	if err := checkNotPadding(request.CommitTxPrevOutputList); err != nil {
+		return nil, err
+	}
+	if len(request.CommitMuSig2PubKeys) > 0 {
+		return nil, fmt.Errorf("%w: doginals musig2 commit keys", ErrUnsupportedOption)
+	}
+	for _, data := range request.InscriptionDataList {
+		if len(data.RevealOutputs) > 0 {
+			return nil, fmt.Errorf("%w: doginals reveal outputs", ErrUnsupportedOption)
+		}
+	}
+	revealOutValue := DogecoinDefaultRevealOutValue
+	if request.RevealOutValue > 0 {
+		revealOutValue = request.RevealOutValue
+	}
+	// use commitTx first input privateKey
+	privateKeyWif, err := btcutil.DecodeWIF(request.CommitTxPrevOutputList[0].PrivateKey)
+	if err != nil {
+		return nil, err
+	}
+	privateKey := privateKeyWif.PrivKey
+
+	// partials of every inscription, and the value each reveal tx spends
+	chains := make([][]*doginalsPartial, len(request.InscriptionDataList))
+	chainValues := make([][]int64, len(request.InscriptionDataList))
+	revealTxFees := make([]int64, 0)
+	revealTxChainLengths := make([]int, len(request.InscriptionDataList))
+	for i, data := range request.InscriptionDataList {
+		revealPkScript, err := AddrToPkScript(data.RevealAddr, backend.Network)
+		if err != nil {
+			return nil, err
+		}
+		partials, err := newDoginalsPartials(privateKey, data)
+		if err != nil {
+			return nil, err
+		}
+		values := make([]int64, len(partials)+1)
+		values[len(partials)] = revealOutValue
+		fees := make([]int64, len(partials))
+		for j := len(partials) - 1; j >= 0; j-- {
+			outPkScript := revealPkScript
+			if j < len(partials)-1 {
+				outPkScript = partials[j+1].pkScript
+			}
+			tx, err := newDoginalsRevealTx(partials[j], nil, values[j+1], outPkScript)
+			if err != nil {
+				return nil, err
+			}
+			fees[j] = dogecoinFee(request.RevealFeeRate, tx.SerializeSize())
+			values[j] = values[j+1] + fees[j]
+		}
+		chains[i] = partials
+		chainValues[i] = values
+		revealTxFees = append(revealTxFees, fees...)
+		revealTxChainLengths[i] = len(partials)
+	}
+
+	inscribedValue := int64(0)
+	for _, values := range chainValues {
+		inscribedValue += values[0]
+	}
+	commitTx, commitTxFee, err := backend.buildCommitTx(request, chains, chainValues)
+	if err != nil && errors.Is(err, ErrInsufficientBalance) {
+		return &InscribeTxs{
+			CommitTx:             "",
+			RevealTxs:            []string{},
+			CommitTxFee:          commitTxFee,
+			RevealTxFees:         revealTxFees,
+			RevealTxChainLengths: revealTxChainLengths,
+			ServiceFee:           request.ServiceFee.amount(len(request.InscriptionDataList), inscribedValue),
+		}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	feeGuard := request.FeeGuard
+	if feeGuard == nil {
+		feeGuard = DefaultDogecoinFeeGuard()
+	}
+	revealTxs := make([]string, 0, len(revealTxFees))
+	commitTxHash := commitTx.TxHash()
+	for i, partials := range chains {
+		revealPkScript, _ := AddrToPkScript(request.InscriptionDataList[i].RevealAddr, backend.Network)
+		prevOutPoint := wire.NewOutPoint(&commitTxHash, uint32(i))
+		for j, partial := range partials {
+			outPkScript := revealPkScript
+			if j < len(partials)-1 {
+				outPkScript = partials[j+1].pkScript
+			}
+			tx, err := newDoginalsRevealTx(partial, prevOutPoint, chainValues[i][j+1], outPkScript)
+			if err != nil {
+				return nil, err
+			}
+			if err := signDoginalsRevealTx(tx, partial, privateKey); err != nil {
+				return nil, err
+			}
+			if err := checkDust(backend, "reveal", len(revealTxs), tx); err != nil {
+				return nil, err
+			}
+			if err := checkDogecoinFee(feeGuard, "reveal", len(revealTxs), tx, chainValues[i][j]); err != nil {
+				return nil, err
+			}
+			if request.VerifyScripts {
+				prevOuts := txscript.NewMultiPrevOutFetcher(nil)
+				prevOuts.AddPrevOut(*prevOutPoint, wire.NewTxOut(chainValues[i][j], partial.pkScript))
+				if err := verifyTx("reveal", len(revealTxs), tx, prevOuts); err != nil {
+					return nil, err
+				}
+			}
+			txHex, err := getTxHex(tx)
+			if err != nil {
+				return nil, err
+			}
+			revealTxs = append(revealTxs, txHex)
+			txHash := tx.TxHash()
+			prevOutPoint = wire.NewOutPoint(&txHash, 0)
+		}
+	}
+
+	commitTxHex, err := getTxHex(commitTx)
+	if err != nil {
+		return nil, err
+	}
+	return &InscribeTxs{
+		CommitTx:             commitTxHex,
+		RevealTxs:            revealTxs,
+		CommitTxFee:          commitTxFee,
+		RevealTxFees:         revealTxFees,
+		RevealTxChainLengths: revealTxChainLengths,
+		ServiceFee:           request.ServiceFee.amount(len(request.InscriptionDataList), inscribedValue),
+	}, nil
+}
+
+func (backend *DogecoinBackend) buildCommitTx(request *InscriptionRequest, chains [][]*doginalsPartial, chainValues [][]int64) (*wire.MsgTx, int64, error) {
+	var privateKeys []*btcec.PrivateKey
+	var multisigs []*multisigInput
+	prevOutFetcher := txscript.NewMultiPrevOutFetcher(nil)
+	totalSenderAmount := int64(0)
+	tx := wire.NewMsgTx(DefaultTxVersion)
+	for _, prevOutput := range request.CommitTxPrevOutputList {
+		privateKey, multisig, err := prevOutput.keys(backend.Network)
+		if err != nil {
+			return nil, 0, err
+		}
+		privateKeys = append(privateKeys, privateKey)
+		multisigs = append(multisigs, multisig)
+		txHash, err := chainhash.NewHashFromStr(prevOutput.TxId)
+		if err != nil {
+			return nil, 0, err
+		}
+		outPoint := wire.NewOutPoint(txHash, prevOutput.VOut)
+		pkScript, err := AddrToPkScript(prevOutput.Address, backend.Network)
+		if err != nil {
+			return nil, 0, err
+		}
+		prevOutFetcher.AddPrevOut(*outPoint, wire.NewTxOut(prevOutput.Amount, pkScript))
+
+		in := wire.NewTxIn(outPoint, nil, nil)
+		in.Sequence = DefaultSequenceNum
+		tx.AddTxIn(in)
+		totalSenderAmount += prevOutput.Amount
+	}
+	totalOutputValue := int64(0)
+	for i, partials := range chains {
+		tx.AddTxOut(wire.NewTxOut(chainValues[i][0], partials[0].pkScript))
+		totalOutputValue += chainValues[i][0]
+	}
+	commitOutputs, err := request.commitOutputs(totalOutputValue)
+	if err != nil {
+		return nil, 0, err
+	}
+	for _, extra := range commitOutputs {
+		pkScript, err := extra.pkScript(backend.Network)
+		if err != nil {
+			return nil, 0, err
+		}
+		tx.AddTxOut(wire.NewTxOut(extra.Amount, pkScript))
+		totalOutputValue += extra.Amount
+	}
+	if err := checkOutputsDust(backend, "commit", 0, len(chains), tx.TxOut[len(chains):]); err != nil {
+		return nil, 0, err
+	}
+	changePkScript, err := AddrToPkScript(request.ChangeAddress, backend.Network)
+	if err != nil {
+		return nil, 0, err
+	}
+	tx.AddTxOut(wire.NewTxOut(0, changePkScript))
+
+	if err := sign(tx, privateKeys, prevOutFetcher, multisigs); err != nil {
+		return nil, 0, err
+	}
+	fee := dogecoinFee(request.CommitFeeRate, tx.SerializeSize())
+	change := totalSenderAmount - totalOutputValue - fee
+	if change < DogecoinDustLimit {
+		tx.TxOut = tx.TxOut[:len(tx.TxOut)-1]
+		if totalSenderAmount-totalOutputValue-dogecoinFee(request.CommitFeeRate, tx.SerializeSize()) < 0 {
+			return nil, fee, ErrInsufficientBalance
+		}
+		change = 0
+	} else {
+		tx.TxOut[len(tx.TxOut)-1].Value = change
+	}
+	if err := sign(tx, privateKeys, prevOutFetcher, multisigs); err != nil {
+		return nil, 0, err
+	}
+
+	feeGuard := request.FeeGuard
+	if feeGuard == nil {
+		feeGuard = DefaultDogecoinFeeGuard()
+	}
+	if err := checkDogecoinFee(feeGuard, "commit", 0, tx, totalSenderAmount); err != nil {
+		return nil, 0, err
+	}
+	if request.VerifyScripts {
+		if err := verifyTx("commit", 0, tx, prevOutFetcher); err != nil {
+			return nil, 0, err
+		}
+	}
+	return tx, totalSenderAmount - totalOutputValue - change, nil
+}
+
+// newDoginalsPartials splits the inscription into the scriptSig partials of the
+// reveal chain, following the doginals reference implementation: "ord", the piece
+// count and content type, then each piece preceded by the number of pieces left.
+func newDoginalsPartials(privateKey *btcec.PrivateKey, data InscriptionData) ([]*doginalsPartial, error) {
+	var pieces [][]byte
+	for i := 0; i < len(data.Body); i += doginalsMaxChunkSize {
+		end := i + doginalsMaxChunkSize
+		if end > len(data.Body) {
+			end = len(data.Body)
+		}
+		pieces = append(pieces, data.Body[i:end])
+	}
+
+	var pushes [][]byte
+	addPush := func(builder *txscript.ScriptBuilder) error {
+		push, err := builder.Script()
+		if err != nil {
+			return err
+		}
+		pushes = append(pushes, push)
+		return nil
+	}
+	if err := addPush(txscript.NewScriptBuilder().AddData([]byte("ord"))); err != nil {
+		return nil, err
+	}
+	if err := addPush(txscript.NewScriptBuilder().AddInt64(int64(len(pieces)))); err != nil {
+		return nil, err
+	}
+	if err := addPush(txscript.NewScriptBuilder().AddData([]byte(data.ContentType))); err != nil {
+		return nil, err
+	}
+	for i, piece := range pieces {
+		if err := addPush(txscript.NewScriptBuilder().AddInt64(int64(len(pieces) - i - 1))); err != nil {
+			return nil, err
+		}
+		if err := addPush(txscript.NewScriptBuilder().AddData(piece)); err != nil {
+			return nil, err
+		}
+	}
+
+	var partials []*doginalsPartial
+	for len(pushes) > 0 {
+		partial := &doginalsPartial{}
+		if len(partials) == 0 {
+			partial.script = append(partial.script, pushes[0]...)
+			partial.pushCount++
+			pushes = pushes[1:]
+		}
+		// pushes after "ord" come in pairs
+		for len(pushes) > 1 && len(partial.script)+len(pushes[0])+len(pushes[1]) <= doginalsMaxPartialSize {
+			partial.script = append(partial.script, pushes[0]...)
+			partial.script = append(partial.script, pushes[1]...)
+			partial.pushCount += 2
+			pushes = pushes[2:]
+		}
+		if partial.pushCount < 2 {
+			return nil, fmt.Errorf("doginals push of %d bytes does not fit in a partial", len(pushes[1]))
+		}
+
+		lockBuilder := txscript.NewScriptBuilder().
+			AddData(privateKey.PubKey().SerializeCompressed()).
+			AddOp(txscript.OP_CHECKSIGVERIFY)
+		for i := 0; i < partial.pushCount; i++ {
+			lockBuilder.AddOp(txscript.OP_DROP)
+		}
+		lock, err := lockBuilder.AddOp(txscript.OP_TRUE).Script()
+		if err != nil {
+			return nil, err
+		}
+		pkScript, err := txscript.NewScriptBuilder().
+			AddOp(txscript.OP_HASH160).
+			AddData(btcutil.Hash160(lock)).
+			AddOp(txscript.OP_EQUAL).
+			Script()
+		if err != nil {
+			return nil, err
+		}
+		partial.lock = lock
+		partial.pkScript = pkScript
+		partials = append(partials, partial)
+	}
+	return partials, nil
+}
+
+// newDoginalsRevealTx builds a reveal tx spending prevOutPoint. The scriptSig holds a
+// signature placeholder of the maximum size until signDoginalsRevealTx replaces it.
+func newDoginalsRevealTx(partial *doginalsPartial, prevOutPoint *wire.OutPoint, value int64, pkScript []byte) (*wire.MsgTx, error) {
+	if prevOutPoint == nil {
+		prevOutPoint = &wire.OutPoint{}
+	}
+	sigScript, err := doginalsSigScript(partial, make([]byte, maxDERSignatureSize))
+	if err != nil {
+		return nil, err
+	}
+	tx := wire.NewMsgTx(DefaultTxVersion)
+	in := wire.NewTxIn(prevOutPoint, sigScript, nil)
+	in.Sequence = DefaultSequenceNum
+	tx.AddTxIn(in)
+	tx.AddTxOut(wire.NewTxOut(value, pkScript))
+	return tx, nil
+}
+
+func signDoginalsRevealTx(tx *wire.MsgTx, partial *doginalsPartial, privateKey *btcec.PrivateKey) error {
+	signature, err := txscript.RawTxInSignature(tx, 0, partial.lock, txscript.SigHashAll, privateKey)
+	if err != nil {
+		return err
+	}
+	sigScript, err := doginalsSigScript(partial, signature)
+	if err != nil {
+		return err
+	}
+	tx.TxIn[0].SignatureScript = sigScript
+	return nil
+}
+
+func doginalsSigScript(partial *doginalsPartial, signature []byte) ([]byte, error) {
+	suffix, err := txscript.NewScriptBuilder().AddData(signature).AddData(partial.lock).Script()
+	if err != nil {
+		return nil, err
+	}
+	return append(append([]byte{}, partial.script...), suffix...), nil
+}
+
+// checkDogecoinFee checks tx against guard, whose MaxFeeRate is in koinu/kB.
+func checkDogecoinFee(guard *FeeGuard, txType string, index int, tx *wire.MsgTx, inputValue int64) error {
+	fee := inputValue
+	for _, out := range tx.TxOut {
+		fee -= out.Value
+	}
+	size := tx.SerializeSize()
+	return guard.checkRateFee(txType, index, fee, int64(size), inputValue, dogecoinFee(guard.MaxFeeRate, size))
+}
+
+// dogecoinFee returns the fee in koinu for size bytes at feeRate koinu/kB, rounded up.
+func dogecoinFee(feeRate FeeRate, size int) int64 {
+	return (int64(size)*feeRate.milliSatPerVByte() + 999999) / 1000000
+}
@@ -0,0 +1,112 @@
+package brc20
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/txscript"
+)
+
+func TestInscribeDogecoin(t *testing.T) {
+	network := DogecoinTestNetParams
+
+	wif, _ := btcutil.DecodeWIF("cPnvkvUYyHcSSS26iD1dkrJdV7k1RoUqJLhn3CYxpo398PdLVE22")
+	address, _ := btcutil.NewAddressPubKeyHash(btcutil.Hash160(wif.PrivKey.PubKey().SerializeCompressed()), network)
+
+	body := bytes.Repeat([]byte("doginals "), 400)
+	request := &InscriptionRequest{
+		CommitTxPrevOutputList: []*PrevOutput{{
+			TxId:       "fcd1a1c33df653427e20159a799e6c1ba28421fd168fe353a54508c956fb382e",
+			VOut:       0,
+			Amount:     500000000,
+			Address:    address.EncodeAddress(),
+			PrivateKey: "cPnvkvUYyHcSSS26iD1dkrJdV7k1RoUqJLhn3CYxpo398PdLVE22",
+		}},
+		CommitFeeRate: 1000000,
+		RevealFeeRate: 1000000,
+		InscriptionDataList: []InscriptionData{
+			{
+				ContentType: "text/plain;charset=utf-8",
+				Body:        body,
+				RevealAddr:  address.EncodeAddress(),
+			},
+			{
+				ContentType: "text/plain;charset=utf-8",
+				Body:        []byte(`{"p":"drc-20","op":"mint","tick":"dogi","amt":"1000"}`),
+				RevealAddr:  address.EncodeAddress(),
+			},
+		},
+		ChangeAddress: address.EncodeAddress(),
+		VerifyScripts: true,
+	}
+	txs, err := Inscribe(network, request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(txs.RevealTxChainLengths) != 2 || txs.RevealTxChainLengths[0] < 2 || txs.RevealTxChainLengths[1] != 1 {
+		t.Fatalf("unexpected reveal chain lengths %v", txs.RevealTxChainLengths)
+	}
+
+	commitTx, _ := newTxFromHex(txs.CommitTx)
+	prevTx := commitTx
+	var pushes [][]byte
+	for i, revealTxHex := range txs.RevealTxs[:txs.RevealTxChainLengths[0]] {
+		revealTx, _ := newTxFromHex(revealTxHex)
+		prevOut := prevTx.TxOut[revealTx.TxIn[0].PreviousOutPoint.Index]
+		if revealTx.TxIn[0].PreviousOutPoint.Hash != prevTx.TxHash() {
+			t.Fatalf("reveal %d does not spend the previous tx", i)
+		}
+		fee := prevOut.Value - revealTx.TxOut[0].Value
+		if fee != txs.RevealTxFees[i] || fee < dogecoinFee(request.RevealFeeRate, revealTx.SerializeSize()) {
+			t.Fatalf("reveal %d fee %d, reported %d, size %d", i, fee, txs.RevealTxFees[i], revealTx.SerializeSize())
+		}
+
+		engine, err := txscript.NewEngine(prevOut.PkScript, revealTx, 0, txscript.StandardVerifyFlags&^txscript.ScriptVerifyWitness,
+			nil, nil, prevOut.Value, txscript.NewCannedPrevOutputFetcher(prevOut.PkScript, prevOut.Value))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := engine.Execute(); err != nil {
+			t.Fatalf("reveal %d: %v", i, err)
+		}
+
+		var sigScriptPushes [][]byte
+		tokenizer := txscript.MakeScriptTokenizer(0, revealTx.TxIn[0].SignatureScript)
+		for tokenizer.Next() {
+			if tokenizer.Data() == nil {
+				// small integer
+				sigScriptPushes = append(sigScriptPushes, []byte{tokenizer.Opcode()})
+			} else {
+				sigScriptPushes = append(sigScriptPushes, tokenizer.Data())
+			}
+		}
+		// drop the signature and the lock script
+		pushes = append(pushes, sigScriptPushes[:len(sigScriptPushes)-2]...)
+		prevTx = revealTx
+	}
+
+	if string(pushes[0]) != "ord" || string(pushes[2]) != "text/plain;charset=utf-8" {
+		t.Fatal("unexpected doginals header")
+	}
+	var revealed []byte
+	for i := 4; i < len(pushes); i += 2 {
+		revealed = append(revealed, pushes[i]...)
+	}
+	if !bytes.Equal(revealed, body) {
+		t.Fatal("revealed body does not match")
+	}
+	t.Log(len(txs.RevealTxs), txs.CommitTxFee, txs.RevealTxFees)
+
+	// the default guard caps the koinu/kB fee rates at 10 DOGE/kB
+	request.InscriptionDataList = request.InscriptionDataList[1:]
+	request.CommitFeeRate, request.RevealFeeRate = 100000000, 100000000
+	if _, err = Inscribe(network, request); err != nil {
+		t.Fatal(err)
+	}
+	request.CommitFeeRate = 2 * DogecoinMaxFeeRate
+	if _, err = Inscribe(network, request); !errors.Is(err, ErrAbsurdFeeRate) {
+		t.Fatalf("expected ErrAbsurdFeeRate, got %v", err)
+	}
+}
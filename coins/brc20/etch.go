@@ -0,0 +1,128 @@
+package brc20
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/btcsuite/btcd/chaincfg"
+)
+
+const (
+	// RuneCommitConfirmations is the number of confirmations the commit output must
+	// have when the reveal is mined, counting the reveal block.
+	RuneCommitConfirmations = 6
+)
+
+var (
+	ErrCommitmentImmature = errors.New("rune commitment immature")
+	ErrReservedRune       = errors.New("reserved rune")
+	ErrSupplyOverflow     = errors.New(RuneFlawSupplyOverflow)
+
+	// runes from AAAAAAAAAAAAAAAAAAAAAAAAAAA up are reserved for etchings without a name
+	firstReservedRune, _ = new(big.Int).SetString("6402364363415443603228541259936211926", 10)
+)
+
+type EtchRequest struct {
+	CommitTxPrevOutputList []*PrevOutput `json:"commitTxPrevOutputList"`
+	CommitFeeRate          FeeRate       `json:"commitFeeRate"`
+	RevealFeeRate          FeeRate       `json:"revealFeeRate"`
+	Etching                *Etching      `json:"etching"`
+	// optional inscription revealed with the etching
+	ContentType string `json:"contentType"`
+	Body        []byte `json:"body"`
+	// receives the inscription and the premine
+	RevealAddr     string    `json:"revealAddr"`
+	RevealOutValue int64     `json:"revealOutValue"`
+	ChangeAddress  string    `json:"changeAddress"`
+	FeeGuard       *FeeGuard `json:"feeGuard"`
+}
+
+type EtchTxs struct {
+	CommitTx    string          `json:"commitTx"`
+	CommitTxFee int64           `json:"commitTxFee"`
+	Reveal      *DeferredReveal `json:"reveal"`
+}
+
+// DeferredReveal is the signed reveal of an etching. It may only be broadcast once
+// the commit has matured, see CheckMaturity.
+type DeferredReveal struct {
+	CommitTxId  string `json:"commitTxId"`
+	RevealTx    string `json:"revealTx"`
+	RevealTxFee int64  `json:"revealTxFee"`
+}
+
+// Etch builds the commit and reveal transactions of a rune etching. The commit pays
+// a tapscript committing to the rune name, and the reveal carries the runestone.
+// The reveal input has a relative lock of RuneCommitConfirmations-1 blocks, so it
+// cannot be mined before the commitment is mature.
+func Etch(network *chaincfg.Params, request *EtchRequest) (*EtchTxs, error) {
+	etching := request.Etching
+	if etching == nil || etching.Rune == nil {
+		return nil, fmt.Errorf("%w: etching requires a rune name", ErrInvalidRune)
+	}
+	if etching.Rune.Value.Cmp(firstReservedRune) >= 0 {
+		return nil, fmt.Errorf("%w: %s", ErrReservedRune, etching.Rune)
+	}
+	if etching.supply() == nil {
+		return nil, fmt.Errorf("%w: %s", ErrSupplyOverflow, etching.Rune)
+	}
+	runestoneOutput, err := (&Runestone{Etching: etching}).TxOutput()
+	if err != nil {
+		return nil, err
+	}
+
+	inscriptionRequest := &InscriptionRequest{
+		CommitTxPrevOutputList: request.CommitTxPrevOutputList,
+		CommitFeeRate:          request.CommitFeeRate,
+		RevealFeeRate:          request.RevealFeeRate,
+		InscriptionDataList: []InscriptionData{{
+			ContentType:   request.ContentType,
+			Body:          request.Body,
+			RevealAddr:    request.RevealAddr,
+			Rune:          etching.Rune.String(),
+			RevealOutputs: []*TxOutput{runestoneOutput},
+		}},
+		RevealOutValue: request.RevealOutValue,
+		ChangeAddress:  request.ChangeAddress,
+		FeeGuard:       request.FeeGuard,
+		revealSequence: RuneCommitConfirmations - 1,
+	}
+	tool, err := newInscriptionTool(network, inscriptionRequest)
+	if err != nil {
+		return nil, err
+	}
+	backend := ChainBackendFor(network)
+	if err := checkDust(backend, "reveal", 0, tool.RevealTx[0]); err != nil {
+		return nil, err
+	}
+
+	commitTx, err := tool.getCommitTxHex()
+	if err != nil {
+		return nil, err
+	}
+	revealTxs, err := tool.getRevealTxHexList()
+	if err != nil {
+		return nil, err
+	}
+	commitTxFee, revealTxFees := tool.calculateFee()
+	return &EtchTxs{
+		CommitTx:    commitTx,
+		CommitTxFee: commitTxFee,
+		Reveal: &DeferredReveal{
+			CommitTxId:  tool.CommitTx.TxHash().String(),
+			RevealTx:    revealTxs[0],
+			RevealTxFee: revealTxFees[0],
+		},
+	}, nil
+}
+
+// CheckMaturity returns ErrCommitmentImmature unless the reveal can be mined in the
+// next block, given the current number of confirmations of the commit tx.
+func (reveal *DeferredReveal) CheckMaturity(confirmations int64) error {
+	if confirmations+1 < RuneCommitConfirmations {
+		return fmt.Errorf("%w: commit %s has %d confirmations, the reveal needs %d", ErrCommitmentImmature,
+			reveal.CommitTxId, confirmations, RuneCommitConfirmations-1)
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+package brc20
+
+import (
+	"bytes"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/btcsuite/btcd/blockchain"
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/chaincfg"
+)
+
+func TestEtch(t *testing.T) {
+	r, spacers, _ := ParseSpacedRune("UNCOMMON•GOODS")
+	request := &EtchRequest{
+		CommitTxPrevOutputList: newTestInscriptionRequest().CommitTxPrevOutputList,
+		CommitFeeRate:          2,
+		RevealFeeRate:          2,
+		Etching: &Etching{
+			Rune:    &r,
+			Spacers: &spacers,
+			Premine: big.NewInt(1000),
+			Terms:   &Terms{Amount: big.NewInt(1), Cap: big.NewInt(100)},
+		},
+		ContentType:   "text/plain;charset=utf-8",
+		Body:          []byte("uncommon goods"),
+		RevealAddr:    "tb1pklh8lqax5l7m2ycypptv2emc4gata2dy28svnwcp9u32wlkenvsspcvhsr",
+		ChangeAddress: "2NF33rckfiQTiE5Guk5ufUdwms8PgmtnEdc",
+	}
+	txs, err := Etch(&chaincfg.TestNet3Params, request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	commitTx, err := newTxFromHex(txs.CommitTx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	revealTx, err := newTxFromHex(txs.Reveal.RevealTx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if revealTx.TxIn[0].PreviousOutPoint.Hash != commitTx.TxHash() || txs.Reveal.CommitTxId != commitTx.TxHash().String() {
+		t.Fatal("reveal does not spend the commit tx")
+	}
+	if revealTx.TxIn[0].Sequence != RuneCommitConfirmations-1 {
+		t.Fatalf("reveal sequence %d", revealTx.TxIn[0].Sequence)
+	}
+	if !bytes.Contains(revealTx.TxIn[0].Witness[1], r.Commitment()) {
+		t.Fatal("rune commitment missing from reveal tapscript")
+	}
+	if len(revealTx.TxOut) != 2 || revealTx.TxOut[0].Value != DefaultRevealOutValue {
+		t.Fatalf("unexpected reveal outputs %d", len(revealTx.TxOut))
+	}
+	runestone, cenotaph := DecipherRunestone(revealTx)
+	if cenotaph != nil || runestone.Etching == nil || runestone.Etching.Rune.String() != "UNCOMMONGOODS" ||
+		*runestone.Etching.Spacers != spacers || runestone.Etching.Premine.Int64() != 1000 {
+		t.Fatal(runestone, cenotaph)
+	}
+	if fee := request.RevealFeeRate.FeeForWeight(blockchain.GetTransactionWeight(btcutil.NewTx(revealTx))); txs.Reveal.RevealTxFee != fee {
+		t.Fatalf("reveal fee %d, want %d", txs.Reveal.RevealTxFee, fee)
+	}
+
+	if err := txs.Reveal.CheckMaturity(4); !errors.Is(err, ErrCommitmentImmature) {
+		t.Fatal(err)
+	}
+	if err := txs.Reveal.CheckMaturity(5); err != nil {
+		t.Fatal(err)
+	}
+
+	request.Etching.Rune = &Rune{Value: firstReservedRune}
+	if _, err := Etch(&chaincfg.TestNet3Params, request); !errors.Is(err, ErrReservedRune) {
+		t.Fatal(err)
+	}
+	request.Etching.Rune = nil
+	if _, err := Etch(&chaincfg.TestNet3Params, request); !errors.Is(err, ErrInvalidRune) {
+		t.Fatal(err)
+	}
+}
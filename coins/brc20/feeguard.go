@@ -0,0 +1,118 @@
+package brc20
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/mempool"
+	"github.com/btcsuite/btcd/wire"
+)
+
+const (
+	FeeGuardMaxFee      = "maxFee"
+	FeeGuardMaxFeeRate  = "maxFeeRate"
+	FeeGuardMaxFeeRatio = "maxFeeRatio"
+)
+
+var (
+	ErrAbsurdFee     = errors.New("absurd fee")
+	ErrAbsurdFeeRate = errors.New("absurd fee rate")
+)
+
+// FeeGuard bounds the fee of every built transaction. A zero limit is not checked.
+// MaxFeeRatio is the fee as a share of the total input value; note that most of a
+// reveal tx input value is fee, so it is rarely useful for Inscribe.
+type FeeGuard struct {
+	MaxFee      int64   `json:"maxFee"`
+	MaxFeeRate  FeeRate `json:"maxFeeRate"`
+	MaxFeeRatio float64 `json:"maxFeeRatio"`
+}
+
+// DefaultFeeGuard returns the guard of every builder given none: it only limits the
+// fee rate to MaxFeeRate. A zero FeeGuard disables the checks.
+func DefaultFeeGuard() *FeeGuard {
+	return &FeeGuard{
+		MaxFeeRate: MaxFeeRate,
+	}
+}
+
+type AbsurdFeeError struct {
+	TxType     string
+	Index      int
+	Limit      string
+	Fee        int64
+	VSize      int64
+	InputValue int64
+}
+
+func (e *AbsurdFeeError) Error() string {
+	return fmt.Sprintf("%s tx(index %d) exceeds %s: fee %d, vsize %d, input value %d",
+		e.TxType, e.Index, e.Limit, e.Fee, e.VSize, e.InputValue)
+}
+
+func (e *AbsurdFeeError) Unwrap() error {
+	if e.Limit == FeeGuardMaxFeeRate {
+		return ErrAbsurdFeeRate
+	}
+	return ErrAbsurdFee
+}
+
+// orDefault returns guard, or DefaultFeeGuard when it is nil.
+func (guard *FeeGuard) orDefault() *FeeGuard {
+	if guard == nil {
+		return DefaultFeeGuard()
+	}
+	return guard
+}
+
+// checkFeeRate rejects a fee rate of zero or below, or above MaxFeeRate, before
+// anything is built with it.
+func (guard *FeeGuard) checkFeeRate(feeRate FeeRate) error {
+	if feeRate <= 0 {
+		return fmt.Errorf("%w: %v sat/vB", ErrInvalidFeeRate, feeRate)
+	}
+	if guard != nil && guard.MaxFeeRate > 0 && feeRate > guard.MaxFeeRate {
+		return fmt.Errorf("%w: %v sat/vB", ErrAbsurdFeeRate, feeRate)
+	}
+	return nil
+}
+
+func (guard *FeeGuard) check(txType string, index int, fee, vsize, inputValue int64) error {
+	if guard == nil {
+		return nil
+	}
+	return guard.checkRateFee(txType, index, fee, vsize, inputValue, guard.MaxFeeRate.FeeForVSize(vsize))
+}
+
+// checkRateFee works like check, with maxRateFee the fee at MaxFeeRate for the tx,
+// for backends that price fees in other units.
+func (guard *FeeGuard) checkRateFee(txType string, index int, fee, vsize, inputValue, maxRateFee int64) error {
+	limit := ""
+	if guard.MaxFee > 0 && fee > guard.MaxFee {
+		limit = FeeGuardMaxFee
+	} else if guard.MaxFeeRate > 0 && fee > maxRateFee {
+		limit = FeeGuardMaxFeeRate
+	} else if guard.MaxFeeRatio > 0 && inputValue > 0 && float64(fee) > float64(inputValue)*guard.MaxFeeRatio {
+		limit = FeeGuardMaxFeeRatio
+	}
+	if limit == "" {
+		return nil
+	}
+	return &AbsurdFeeError{
+		TxType:     txType,
+		Index:      index,
+		Limit:      limit,
+		Fee:        fee,
+		VSize:      vsize,
+		InputValue: inputValue,
+	}
+}
+
+func (guard *FeeGuard) checkTx(txType string, index int, tx *wire.MsgTx, inputValue int64) error {
+	fee := inputValue
+	for _, out := range tx.TxOut {
+		fee -= out.Value
+	}
+	return guard.check(txType, index, fee, mempool.GetTxVirtualSize(btcutil.NewTx(tx)), inputValue)
+}
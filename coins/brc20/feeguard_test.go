@@ -0,0 +1,58 @@
+package brc20
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/btcsuite/btcd/chaincfg"
+)
+
+func TestFeeGuard(t *testing.T) {
+	network := &chaincfg.TestNet3Params
+
+	request := newTestInscriptionRequest()
+	request.FeeGuard = &FeeGuard{MaxFee: 300}
+	_, err := Inscribe(network, request)
+	var feeErr *AbsurdFeeError
+	if !errors.As(err, &feeErr) || feeErr.Limit != FeeGuardMaxFee || !errors.Is(err, ErrAbsurdFee) {
+		t.Fatalf("expected maxFee AbsurdFeeError, got %v", err)
+	}
+	t.Log(err)
+
+	// typo in the output amount: 24935 instead of 249352
+	inputs := []*TxInput{{
+		TxId:       "25b9d08a26c8d47795301dd47a861cff0459d14f27fbd41cffaca17d9aa20f87",
+		VOut:       uint32(0),
+		Amount:     int64(2493520),
+		Address:    "tb1qtsq9c4fje6qsmheql8gajwtrrdrs38kdzeersc",
+		PrivateKey: "cPnvkvUYyHcSSS26iD1dkrJdV7k1RoUqJLhn3CYxpo398PdLVE22",
+	}}
+	outputs := []*TxOutput{{
+		Address: "tb1pklh8lqax5l7m2ycypptv2emc4gata2dy28svnwcp9u32wlkenvsspcvhsr",
+		Amount:  int64(24935),
+	}}
+	_, err = Transfer(inputs, outputs, network)
+	if !errors.As(err, &feeErr) || feeErr.TxType != "transfer" || !errors.Is(err, ErrAbsurdFeeRate) {
+		t.Fatalf("expected maxFeeRate AbsurdFeeError, got %v", err)
+	}
+	t.Log(err)
+
+	// a zero guard checks nothing
+	if _, err = Transfer(inputs, outputs, network, WithFeeGuard(&FeeGuard{})); err != nil {
+		t.Fatal(err)
+	}
+
+	guard := WithFeeGuard(&FeeGuard{MaxFeeRatio: 0.5})
+	outputs[0].Amount = 1246759
+	_, err = Transfer(inputs, outputs, network, guard)
+	if !errors.As(err, &feeErr) || feeErr.Limit != FeeGuardMaxFeeRatio {
+		t.Fatalf("expected maxFeeRatio AbsurdFeeError, got %v", err)
+	}
+	outputs[0].Amount = 1246760
+	if _, err = Transfer(inputs, outputs, network, guard); err != nil {
+		t.Fatal(err)
+	}
+	if DefaultFeeGuard().MaxFeeRatio != 0 {
+		t.Fatal("the default fee guard was changed")
+	}
+}
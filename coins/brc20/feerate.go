@@ -0,0 +1,24 @@
+package brc20
+
+import (
+	"math"
+)
+
+// FeeRate is a fee rate in sat/vB and may be fractional, e.g. 1.5 or 0.5.
+// Fees are computed in millisats from the transaction weight and rounded up to whole sats.
+type FeeRate float64
+
+func (rate FeeRate) milliSatPerVByte() int64 {
+	return int64(math.Round(float64(rate) * 1000))
+}
+
+// FeeForVSize returns the fee for vsize virtual bytes, rounded up.
+func (rate FeeRate) FeeForVSize(vsize int64) int64 {
+	return (vsize*rate.milliSatPerVByte() + 999) / 1000
+}
+
+// FeeForWeight returns the fee for a transaction of the given weight. As in bitcoin
+// core the weight is first rounded up to virtual bytes.
+func (rate FeeRate) FeeForWeight(weight int64) int64 {
+	return rate.FeeForVSize((weight + 3) / 4)
+}
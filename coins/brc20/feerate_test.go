@@ -0,0 +1,57 @@
+package brc20
+
+import (
+	"testing"
+
+	"github.com/btcsuite/btcd/blockchain"
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/chaincfg"
+)
+
+func TestFeeRate(t *testing.T) {
+	tests := []struct {
+		rate   FeeRate
+		weight int64
+		fee    int64
+	}{
+		{1, 400, 100},
+		{1, 401, 101},
+		{1.5, 400, 150},
+		{1.5, 401, 152},
+		{0.5, 561, 71},
+		{1.1, 400, 110},
+		{0.1, 4, 1},
+		{0, 400, 0},
+	}
+	for _, test := range tests {
+		if fee := test.rate.FeeForWeight(test.weight); fee != test.fee {
+			t.Errorf("%v sat/vB for weight %d: got %d, want %d", test.rate, test.weight, fee, test.fee)
+		}
+	}
+}
+
+func TestInscribeFractionalFeeRate(t *testing.T) {
+	network := &chaincfg.TestNet3Params
+
+	request := newTestInscriptionRequest()
+	request.CommitFeeRate = 1.5
+	request.RevealFeeRate = 1.5
+	txs, err := Inscribe(network, request)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	commitTx, _ := newTxFromHex(txs.CommitTx)
+	commitWeight := blockchain.GetTransactionWeight(btcutil.NewTx(commitTx))
+	if txs.CommitTxFee < request.CommitFeeRate.FeeForWeight(commitWeight) {
+		t.Fatalf("commit fee %d below %v sat/vB for weight %d", txs.CommitTxFee, request.CommitFeeRate, commitWeight)
+	}
+	for i, revealTxHex := range txs.RevealTxs {
+		revealTx, _ := newTxFromHex(revealTxHex)
+		revealWeight := blockchain.GetTransactionWeight(btcutil.NewTx(revealTx))
+		if txs.RevealTxFees[i] < request.RevealFeeRate.FeeForWeight(revealWeight) {
+			t.Fatalf("reveal %d fee %d below %v sat/vB for weight %d", i, txs.RevealTxFees[i], request.RevealFeeRate, revealWeight)
+		}
+	}
+	t.Log(txs.CommitTxFee, txs.RevealTxFees)
+}
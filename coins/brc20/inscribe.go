@@ -32,6 +32,10 @@ type InscriptionData struct {
 	ContentType string `json:"contentType"`
 	Body        []byte `json:"body"`
 	RevealAddr  string `json:"revealAddr"`
+	// Rune is the name of a rune whose commitment is added to the envelope for an etching
+	Rune string `json:"rune"`
+	// RevealOutputs are added to the reveal tx after the inscription output
+	RevealOutputs []*TxOutput `json:"revealOutputs"`
 }
 
 type PrevOutput struct {
@@ -40,15 +44,41 @@ type PrevOutput struct {
 	Amount     int64  `json:"amount"`
 	Address    string `json:"address"`
 	PrivateKey string `json:"privateKey"`
+	// IsPadding marks a padding utxo from PrepareDummyUTXOs, which Inscribe refuses to spend
+	IsPadding bool `json:"isPadding,omitempty"`
+	// hex multisig script of a p2wsh or p2sh-p2wsh output, or of a p2sh output
+	WitnessScript string `json:"witnessScript,omitempty"`
+	RedeemScript  string `json:"redeemScript,omitempty"`
+	// signing keys besides PrivateKey
+	PrivateKeys []string `json:"privateKeys,omitempty"`
+	// BIP-32 path of PrivateKey, set from it by a wallet when PrivateKey is empty
+	DerivationPath string `json:"derivationPath,omitempty"`
 }
 
 type InscriptionRequest struct {
 	CommitTxPrevOutputList []*PrevOutput     `json:"commitTxPrevOutputList"`
-	CommitFeeRate          int64             `json:"commitFeeRate"`
-	RevealFeeRate          int64             `json:"revealFeeRate"`
+	CommitFeeRate          FeeRate           `json:"commitFeeRate"`
+	RevealFeeRate          FeeRate           `json:"revealFeeRate"`
 	InscriptionDataList    []InscriptionData `json:"inscriptionDataList"`
 	RevealOutValue         int64             `json:"revealOutValue"`
 	ChangeAddress          string            `json:"changeAddress"`
+	FeeGuard               *FeeGuard         `json:"feeGuard"`
+	// CommitOutputs are added to the commit tx after the reveal funding outputs and
+	// before the change, e.g. a platform fee or OP_RETURN data
+	CommitOutputs []*TxOutput `json:"commitOutputs"`
+	// ServiceFee is paid by the commit tx before CommitOutputs
+	ServiceFee *ServiceFee `json:"serviceFee"`
+	// CommitMuSig2PubKeys are the hex public keys whose MuSig2 aggregate replaces the
+	// first commit input key as the internal key and the inscription script key of
+	// the commit addresses. The reveal txs are then left unsigned, for the
+	// MuSig2PartialSignReveal and MuSig2CombineReveal rounds.
+	CommitMuSig2PubKeys []string `json:"commitMuSig2PubKeys,omitempty"`
+	// VerifyScripts runs every input of the commit and reveal txs through the script
+	// engine
+	VerifyScripts bool `json:"verifyScripts,omitempty"`
+
+	// set by Etch to lock the reveal input until the rune commitment matures
+	revealSequence uint32
 }
 
 type InscribeTxs struct {
@@ -56,6 +86,13 @@ type InscribeTxs struct {
 	RevealTxs    []string `json:"revealTxs"`
 	CommitTxFee  int64    `json:"commitTxFee"`
 	RevealTxFees []int64  `json:"revealTxFees"`
+	// ServiceFee is the service fee output value, not included in CommitTxFee
+	ServiceFee int64 `json:"serviceFee"`
+	// CommitTapMerkleRoots are the hex script tree roots of the commit outputs, for
+	// a key path spend with CommitMuSig2PubKeys, set when they are
+	CommitTapMerkleRoots []string `json:"commitTapMerkleRoots,omitempty"`
+	// set by backends that reveal an inscription over a chain of transactions
+	RevealTxChainLengths []int `json:"revealTxChainLengths,omitempty"`
 }
 
 type inscriptionTxCtxData struct {
@@ -64,12 +101,14 @@ type inscriptionTxCtxData struct {
 	CommitTxAddressPkScript []byte
 	ControlBlockWitness     []byte
 	RevealTxPrevOutput      *wire.TxOut
+	TapMerkleRoot           []byte
 }
 
 type InscriptionTool struct {
 	Network                   *chaincfg.Params
 	CommitTxPrevOutputFetcher *txscript.MultiPrevOutFetcher
 	CommitTxPrivateKeyList    []*btcec.PrivateKey
+	commitTxMultisigList      []*multisigInput
 	InscriptionTxCtxDataList  []*inscriptionTxCtxData
 	RevealTxPrevOutputFetcher *txscript.MultiPrevOutFetcher
 	CommitTxPrevOutputList    []*PrevOutput
@@ -80,19 +119,37 @@ type InscriptionTool struct {
 }
 
 func Inscribe(network *chaincfg.Params, request *InscriptionRequest) (*InscribeTxs, error) {
-	tool, err := newInscriptionTool(network, request)
+	return ChainBackendFor(network).Inscribe(request)
+}
+
+func inscribeTaproot(backend *TaprootBackend, request *InscriptionRequest) (*InscribeTxs, error) {
+	tool, err := newInscriptionTool(backend.Network, request)
 	if err != nil && errors.Is(err, ErrInsufficientBalance) {
 		return &InscribeTxs{
 			CommitTx:     "",
 			RevealTxs:    []string{},
 			CommitTxFee:  tool.MustCommitTxFee,
 			RevealTxFees: tool.MustRevealTxFees,
+			ServiceFee:   request.ServiceFee.amount(len(request.InscriptionDataList), tool.inscribedValue()),
 		}, nil
 	}
 
 	if err != nil {
 		return nil, err
 	}
+	for i, tx := range tool.RevealTx {
+		if err := checkDust(backend, "reveal", i, tx); err != nil {
+			return nil, err
+		}
+	}
+	commitOutputs, err := request.commitOutputs(tool.inscribedValue())
+	if err != nil {
+		return nil, err
+	}
+	extraOutputs := tool.CommitTx.TxOut[len(tool.RevealTx) : len(tool.RevealTx)+len(commitOutputs)]
+	if err := checkOutputsDust(backend, "commit", 0, len(tool.RevealTx), extraOutputs); err != nil {
+		return nil, err
+	}
 
 	commitTx, err := tool.getCommitTxHex()
 	if err != nil {
@@ -105,27 +162,42 @@ func Inscribe(network *chaincfg.Params, request *InscriptionRequest) (*InscribeT
 
 	commitTxFee, revealTxFees := tool.calculateFee()
 
+	var commitTapMerkleRoots []string
+	if len(request.CommitMuSig2PubKeys) > 0 {
+		for _, ctxData := range tool.InscriptionTxCtxDataList {
+			commitTapMerkleRoots = append(commitTapMerkleRoots, hex.EncodeToString(ctxData.TapMerkleRoot))
+		}
+	}
+
 	return &InscribeTxs{
-		CommitTx:     commitTx,
-		RevealTxs:    revealTxs,
-		CommitTxFee:  commitTxFee,
-		RevealTxFees: revealTxFees,
+		CommitTx:             commitTx,
+		RevealTxs:            revealTxs,
+		CommitTxFee:          commitTxFee,
+		RevealTxFees:         revealTxFees,
+		ServiceFee:           request.ServiceFee.amount(len(request.InscriptionDataList), tool.inscribedValue()),
+		CommitTapMerkleRoots: commitTapMerkleRoots,
 	}, nil
 }
 
 func newInscriptionTool(network *chaincfg.Params, request *InscriptionRequest) (*InscriptionTool, error) {
+	if err := checkNotPadding(request.CommitTxPrevOutputList); err != nil {
+		return nil, err
+	}
 	var commitTxPrivateKeyList []*btcec.PrivateKey
+	var commitTxMultisigList []*multisigInput
 	for _, prevOutput := range request.CommitTxPrevOutputList {
-		privateKeyWif, err := btcutil.DecodeWIF(prevOutput.PrivateKey)
+		privateKey, multisig, err := prevOutput.keys(network)
 		if err != nil {
 			return nil, err
 		}
-		commitTxPrivateKeyList = append(commitTxPrivateKeyList, privateKeyWif.PrivKey)
+		commitTxPrivateKeyList = append(commitTxPrivateKeyList, privateKey)
+		commitTxMultisigList = append(commitTxMultisigList, multisig)
 	}
 	tool := &InscriptionTool{
 		Network:                   network,
 		CommitTxPrevOutputFetcher: txscript.NewMultiPrevOutFetcher(nil),
 		CommitTxPrivateKeyList:    commitTxPrivateKeyList,
+		commitTxMultisigList:      commitTxMultisigList,
 		InscriptionTxCtxDataList:  make([]*inscriptionTxCtxData, len(request.InscriptionDataList)),
 		RevealTxPrevOutputFetcher: txscript.NewMultiPrevOutFetcher(nil),
 		CommitTxPrevOutputList:    request.CommitTxPrevOutputList,
@@ -138,6 +210,7 @@ func newInscriptionTool(network *chaincfg.Params, request *InscriptionRequest) (
 
 func (tool *InscriptionTool) initTool(network *chaincfg.Params, request *InscriptionRequest) error {
 	destinations := make([]string, len(request.InscriptionDataList))
+	revealOutputs := make([][]*TxOutput, len(request.InscriptionDataList))
 	revealOutValue := DefaultRevealOutValue
 	if request.RevealOutValue > 0 {
 		revealOutValue = request.RevealOutValue
@@ -149,44 +222,78 @@ func (tool *InscriptionTool) initTool(network *chaincfg.Params, request *Inscrip
 		}
 		tool.InscriptionTxCtxDataList[i] = inscriptionTxCtxData
 		destinations[i] = request.InscriptionDataList[i].RevealAddr
+		revealOutputs[i] = request.InscriptionDataList[i].RevealOutputs
+	}
+	totalRevealPrevOutputValue, err := tool.buildEmptyRevealTx(destinations, revealOutValue, request.RevealFeeRate, revealOutputs, request.revealSequence)
+	if err != nil {
+		return err
 	}
-	totalRevealPrevOutputValue, err := tool.buildEmptyRevealTx(destinations, revealOutValue, request.RevealFeeRate)
+	commitOutputs, err := request.commitOutputs(totalRevealPrevOutputValue)
 	if err != nil {
 		return err
 	}
-	err = tool.buildCommitTx(request.CommitTxPrevOutputList, request.ChangeAddress, totalRevealPrevOutputValue, request.CommitFeeRate)
+	err = tool.buildCommitTx(request.CommitTxPrevOutputList, request.ChangeAddress, totalRevealPrevOutputValue, request.CommitFeeRate, commitOutputs)
 	if err != nil {
 		return err
 	}
 	err = tool.signCommitTx()
 	if err != nil {
-		return errors.New("sign commit tx error")
+		return fmt.Errorf("sign commit tx error: %w", err)
 	}
 	err = tool.completeRevealTx()
 	if err != nil {
 		return err
 	}
+	feeGuard := request.FeeGuard.orDefault()
+	if err = tool.checkFees(feeGuard); err != nil {
+		return err
+	}
+	if request.VerifyScripts {
+		return tool.verifyScripts()
+	}
 	return nil
 }
 
 func createInscriptionTxCtxData(network *chaincfg.Params, inscriptionRequest *InscriptionRequest, indexOfInscriptionDataList int) (*inscriptionTxCtxData, error) {
-	// use commitTx first input privateKey
-	privateKeyWif, err := btcutil.DecodeWIF(inscriptionRequest.CommitTxPrevOutputList[0].PrivateKey)
-	if err != nil {
-		return nil, err
+	// use commitTx first input privateKey, or the musig2 aggregate key with no private key
+	var privateKey *btcec.PrivateKey
+	var internalKey *btcec.PublicKey
+	if len(inscriptionRequest.CommitMuSig2PubKeys) > 0 {
+		aggregateKey, err := MuSig2AggregateKey(inscriptionRequest.CommitMuSig2PubKeys)
+		if err != nil {
+			return nil, err
+		}
+		if internalKey, err = parseInternalKey(aggregateKey); err != nil {
+			return nil, err
+		}
+	} else {
+		privateKeyWif, err := btcutil.DecodeWIF(inscriptionRequest.CommitTxPrevOutputList[0].PrivateKey)
+		if err != nil {
+			return nil, err
+		}
+		privateKey = privateKeyWif.PrivKey
+		internalKey = privateKey.PubKey()
 	}
-	privateKey := privateKeyWif.PrivKey
 
 	inscriptionBuilder := txscript.NewScriptBuilder().
-		AddData(schnorr.SerializePubKey(privateKey.PubKey())).
+		AddData(schnorr.SerializePubKey(internalKey)).
 		AddOp(txscript.OP_CHECKSIG).
 		AddOp(txscript.OP_FALSE).
 		AddOp(txscript.OP_IF).
 		AddData([]byte("ord")).
 		AddOp(txscript.OP_DATA_1).
 		AddOp(txscript.OP_DATA_1).
-		AddData([]byte(inscriptionRequest.InscriptionDataList[indexOfInscriptionDataList].ContentType)).
-		AddOp(txscript.OP_0)
+		AddData([]byte(inscriptionRequest.InscriptionDataList[indexOfInscriptionDataList].ContentType))
+	if runeName := inscriptionRequest.InscriptionDataList[indexOfInscriptionDataList].Rune; runeName != "" {
+		r, _, err := ParseSpacedRune(runeName)
+		if err != nil {
+			return nil, err
+		}
+		inscriptionBuilder.AddOp(txscript.OP_DATA_1).
+			AddOp(InscriptionTagRune).
+			AddFullData(r.Commitment())
+	}
+	inscriptionBuilder.AddOp(txscript.OP_0)
 
 	maxChunkSize := 520
 	bodySize := len(inscriptionRequest.InscriptionDataList[indexOfInscriptionDataList].Body)
@@ -206,18 +313,18 @@ func createInscriptionTxCtxData(network *chaincfg.Params, inscriptionRequest *In
 	inscriptionScript = append(inscriptionScript, txscript.OP_ENDIF)
 
 	proof := &txscript.TapscriptProof{
-		TapLeaf:  txscript.NewBaseTapLeaf(schnorr.SerializePubKey(privateKey.PubKey())),
+		TapLeaf:  txscript.NewBaseTapLeaf(schnorr.SerializePubKey(internalKey)),
 		RootNode: txscript.NewBaseTapLeaf(inscriptionScript),
 	}
 
-	controlBlock := proof.ToControlBlock(privateKey.PubKey())
+	controlBlock := proof.ToControlBlock(internalKey)
 	controlBlockWitness, err := controlBlock.ToBytes()
 	if err != nil {
 		return nil, err
 	}
 
 	tapHash := proof.RootNode.TapHash()
-	commitTxAddress, err := btcutil.NewAddressTaproot(schnorr.SerializePubKey(txscript.ComputeTaprootOutputKey(privateKey.PubKey(), tapHash[:])), network)
+	commitTxAddress, err := btcutil.NewAddressTaproot(schnorr.SerializePubKey(txscript.ComputeTaprootOutputKey(internalKey, tapHash[:])), network)
 	if err != nil {
 		return nil, err
 	}
@@ -231,13 +338,18 @@ func createInscriptionTxCtxData(network *chaincfg.Params, inscriptionRequest *In
 		InscriptionScript:       inscriptionScript,
 		CommitTxAddressPkScript: commitTxAddressPkScript,
 		ControlBlockWitness:     controlBlockWitness,
+		TapMerkleRoot:           tapHash[:],
 	}, nil
 }
 
-func (tool *InscriptionTool) buildEmptyRevealTx(destination []string, revealOutValue, revealFeeRate int64) (int64, error) {
+func (tool *InscriptionTool) buildEmptyRevealTx(destination []string, revealOutValue int64, revealFeeRate FeeRate,
+	extraOutputs [][]*TxOutput, sequence uint32) (int64, error) {
+	if sequence == 0 {
+		sequence = DefaultSequenceNum
+	}
 	addTxInTxOutIntoRevealTx := func(tx *wire.MsgTx, index int) error {
 		in := wire.NewTxIn(&wire.OutPoint{Index: uint32(index)}, nil, nil)
-		in.Sequence = DefaultSequenceNum
+		in.Sequence = sequence
 		tx.AddTxIn(in)
 		scriptPubKey, err := AddrToPkScript(destination[index], tool.Network)
 		if err != nil {
@@ -245,6 +357,13 @@ func (tool *InscriptionTool) buildEmptyRevealTx(destination []string, revealOutV
 		}
 		out := wire.NewTxOut(revealOutValue, scriptPubKey)
 		tx.AddTxOut(out)
+		for _, extra := range extraOutputs[index] {
+			pkScript, err := extra.pkScript(tool.Network)
+			if err != nil {
+				return err
+			}
+			tx.AddTxOut(wire.NewTxOut(extra.Amount, pkScript))
+		}
 		return nil
 	}
 
@@ -257,22 +376,18 @@ func (tool *InscriptionTool) buildEmptyRevealTx(destination []string, revealOutV
 		if err := addTxInTxOutIntoRevealTx(tx, i); err != nil {
 			return 0, err
 		}
-		prevOutputValue := revealOutValue + int64(tx.SerializeSize())*revealFeeRate
-		emptySignature := make([]byte, 64)
-		emptyControlBlockWitness := make([]byte, 33)
-		fee := (int64(wire.TxWitness{
-			emptySignature,
-			tool.InscriptionTxCtxDataList[i].InscriptionScript,
-			emptyControlBlockWitness,
-		}.SerializeSize()+2+3) / 4) * revealFeeRate // +2 encoding bytes , +3 for rounding up, /4 divide by weight
-		prevOutputValue += fee
+		fee := tool.solveRevealTxFee(tx, i, revealFeeRate)
+		prevOutputValue := fee
+		for _, out := range tx.TxOut {
+			prevOutputValue += out.Value
+		}
 		tool.InscriptionTxCtxDataList[i].RevealTxPrevOutput = &wire.TxOut{
 			PkScript: tool.InscriptionTxCtxDataList[i].CommitTxAddressPkScript,
 			Value:    prevOutputValue,
 		}
 		totalPrevOutputValue += prevOutputValue
 		revealTx[i] = tx
-		mustRevealTxFees[i] = int64(tx.SerializeSize())*revealFeeRate + fee
+		mustRevealTxFees[i] = fee
 	}
 	tool.RevealTx = revealTx
 	tool.MustRevealTxFees = mustRevealTxFees
@@ -280,7 +395,34 @@ func (tool *InscriptionTool) buildEmptyRevealTx(destination []string, revealOutV
 	return totalPrevOutputValue, nil
 }
 
-func (tool *InscriptionTool) buildCommitTx(commitTxPrevOutputList []*PrevOutput, changeAddress string, totalRevealPrevOutputValue, commitFeeRate int64) error {
+// solveRevealTxFee sizes the reveal tx with a dummy witness of the exact final size
+// (a SigHashDefault schnorr signature is always 64 bytes), so the signed reveal pays
+// exactly revealFeeRate for its weight. The reveal outputs do not depend on the commit
+// output value, so the first solution is already the fixed point.
+func (tool *InscriptionTool) solveRevealTxFee(tx *wire.MsgTx, index int, revealFeeRate FeeRate) int64 {
+	tx.TxIn[0].Witness = wire.TxWitness{
+		make([]byte, schnorr.SignatureSize),
+		tool.InscriptionTxCtxDataList[index].InscriptionScript,
+		tool.InscriptionTxCtxDataList[index].ControlBlockWitness,
+	}
+	weight := blockchain.GetTransactionWeight(btcutil.NewTx(tx))
+	tx.TxIn[0].Witness = nil
+	return revealFeeRate.FeeForWeight(weight)
+}
+
+// inscribedValue is the value of the reveal funding outputs of the commit tx.
+func (tool *InscriptionTool) inscribedValue() int64 {
+	value := int64(0)
+	for _, ctxData := range tool.InscriptionTxCtxDataList {
+		if ctxData != nil && ctxData.RevealTxPrevOutput != nil {
+			value += ctxData.RevealTxPrevOutput.Value
+		}
+	}
+	return value
+}
+
+func (tool *InscriptionTool) buildCommitTx(commitTxPrevOutputList []*PrevOutput, changeAddress string, totalRevealPrevOutputValue int64, commitFeeRate FeeRate,
+	extraOutputs []*TxOutput) error {
 	totalSenderAmount := btcutil.Amount(0)
 	tx := wire.NewMsgTx(DefaultTxVersion)
 	changePkScript, err := AddrToPkScript(changeAddress, tool.Network)
@@ -309,26 +451,35 @@ func (tool *InscriptionTool) buildCommitTx(commitTxPrevOutputList []*PrevOutput,
 	for i := range tool.InscriptionTxCtxDataList {
 		tx.AddTxOut(tool.InscriptionTxCtxDataList[i].RevealTxPrevOutput)
 	}
+	totalOutputValue := totalRevealPrevOutputValue
+	for _, extra := range extraOutputs {
+		pkScript, err := extra.pkScript(tool.Network)
+		if err != nil {
+			return err
+		}
+		tx.AddTxOut(wire.NewTxOut(extra.Amount, pkScript))
+		totalOutputValue += extra.Amount
+	}
 
 	tx.AddTxOut(wire.NewTxOut(0, changePkScript))
 
 	txForEstimate := wire.NewMsgTx(DefaultTxVersion)
 	txForEstimate.TxIn = tx.TxIn
 	txForEstimate.TxOut = tx.TxOut
-	if err := sign(txForEstimate, tool.CommitTxPrivateKeyList, tool.CommitTxPrevOutputFetcher); err != nil {
+	if err := sign(txForEstimate, tool.CommitTxPrivateKeyList, tool.CommitTxPrevOutputFetcher, tool.commitTxMultisigList); err != nil {
 		return err
 	}
 
-	fee := btcutil.Amount(mempool.GetTxVirtualSize(btcutil.NewTx(txForEstimate))) * btcutil.Amount(commitFeeRate)
-	changeAmount := totalSenderAmount - btcutil.Amount(totalRevealPrevOutputValue) - fee
+	fee := btcutil.Amount(commitFeeRate.FeeForWeight(blockchain.GetTransactionWeight(btcutil.NewTx(txForEstimate))))
+	changeAmount := totalSenderAmount - btcutil.Amount(totalOutputValue) - fee
 	if changeAmount > 0 {
 		tx.TxOut[len(tx.TxOut)-1].Value = int64(changeAmount)
 	} else {
 		tx.TxOut = tx.TxOut[:len(tx.TxOut)-1]
 		if changeAmount < 0 {
 			txForEstimate.TxOut = txForEstimate.TxOut[:len(txForEstimate.TxOut)-1]
-			feeWithoutChange := btcutil.Amount(mempool.GetTxVirtualSize(btcutil.NewTx(txForEstimate))) * btcutil.Amount(commitFeeRate)
-			if totalSenderAmount-btcutil.Amount(totalRevealPrevOutputValue)-feeWithoutChange < 0 {
+			feeWithoutChange := btcutil.Amount(commitFeeRate.FeeForWeight(blockchain.GetTransactionWeight(btcutil.NewTx(txForEstimate))))
+			if totalSenderAmount-btcutil.Amount(totalOutputValue)-feeWithoutChange < 0 {
 				tool.MustCommitTxFee = int64(fee)
 				return ErrInsufficientBalance
 			}
@@ -351,6 +502,14 @@ func (tool *InscriptionTool) completeRevealTx() error {
 	}
 	for i := range tool.InscriptionTxCtxDataList {
 		revealTx := tool.RevealTx[i]
+		if tool.InscriptionTxCtxDataList[i].PrivateKey == nil {
+			// left for the musig2 signing rounds
+			revealTx.TxIn[0].Witness = wire.TxWitness{
+				tool.InscriptionTxCtxDataList[i].InscriptionScript,
+				tool.InscriptionTxCtxDataList[i].ControlBlockWitness,
+			}
+			continue
+		}
 		witnessArray, err := txscript.CalcTapscriptSignaturehash(
 			txscript.NewTxSigHashes(revealTx, tool.RevealTxPrevOutputFetcher),
 			txscript.SigHashDefault, revealTx, 0, tool.RevealTxPrevOutputFetcher,
@@ -371,8 +530,8 @@ func (tool *InscriptionTool) completeRevealTx() error {
 		tool.RevealTx[i].TxIn[0].Witness = witness
 	}
 	// check tx max tx wight
-	for i, tx := range tool.RevealTx {
-		revealWeight := blockchain.GetTransactionWeight(btcutil.NewTx(tx))
+	for i := range tool.RevealTx {
+		revealWeight := blockchain.GetTransactionWeight(btcutil.NewTx(tool.signedRevealTx(i)))
 		if revealWeight > MaxStandardTxWeight {
 			return fmt.Errorf("reveal(index %d) transaction weight greater than %d (MAX_STANDARD_TX_WEIGHT): %d", i, MaxStandardTxWeight, revealWeight)
 		}
@@ -380,28 +539,92 @@ func (tool *InscriptionTool) completeRevealTx() error {
 	return nil
 }
 
+func (tool *InscriptionTool) checkFees(feeGuard *FeeGuard) error {
+	commitTxInputValue := int64(0)
+	for _, in := range tool.CommitTx.TxIn {
+		commitTxInputValue += tool.CommitTxPrevOutputFetcher.FetchPrevOutput(in.PreviousOutPoint).Value
+	}
+	if err := feeGuard.checkTx("commit", 0, tool.CommitTx, commitTxInputValue); err != nil {
+		return err
+	}
+	for i := range tool.RevealTx {
+		if err := feeGuard.checkTx("reveal", i, tool.signedRevealTx(i), tool.InscriptionTxCtxDataList[i].RevealTxPrevOutput.Value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// signedRevealTx returns reveal tx i, with a dummy signature of the final size when
+// it is left unsigned.
+func (tool *InscriptionTool) signedRevealTx(i int) *wire.MsgTx {
+	tx := tool.RevealTx[i]
+	if tool.InscriptionTxCtxDataList[i].PrivateKey != nil {
+		return tx
+	}
+	signed := tx.Copy()
+	signed.TxIn[0].Witness = append(wire.TxWitness{make([]byte, schnorr.SignatureSize)}, signed.TxIn[0].Witness...)
+	return signed
+}
+
+func (tool *InscriptionTool) verifyScripts() error {
+	if err := verifyTx("commit", 0, tool.CommitTx, tool.CommitTxPrevOutputFetcher); err != nil {
+		return err
+	}
+	for i, tx := range tool.RevealTx {
+		if tool.InscriptionTxCtxDataList[i].PrivateKey == nil {
+			continue
+		}
+		if err := verifyTx("reveal", i, tx, tool.RevealTxPrevOutputFetcher); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (tool *InscriptionTool) signCommitTx() error {
-	return sign(tool.CommitTx, tool.CommitTxPrivateKeyList, tool.CommitTxPrevOutputFetcher)
+	return sign(tool.CommitTx, tool.CommitTxPrivateKeyList, tool.CommitTxPrevOutputFetcher, tool.commitTxMultisigList)
 }
 
-func sign(tx *wire.MsgTx, privateKeys []*btcec.PrivateKey, prevOutFetcher *txscript.MultiPrevOutFetcher) error {
+// sign signs every input of tx with its private key, or with its multisig when
+// multisigs has one for the input.
+func sign(tx *wire.MsgTx, privateKeys []*btcec.PrivateKey, prevOutFetcher *txscript.MultiPrevOutFetcher, multisigs []*multisigInput) error {
 	for i, in := range tx.TxIn {
+		if i < len(multisigs) && multisigs[i] != nil {
+			if err := multisigs[i].signTx(tx, i, prevOutFetcher); err != nil {
+				return err
+			}
+			continue
+		}
 		prevOut := prevOutFetcher.FetchPrevOutput(in.PreviousOutPoint)
 		txSigHashes := txscript.NewTxSigHashes(tx, prevOutFetcher)
 		privKey := privateKeys[i]
-		if txscript.IsPayToTaproot(prevOut.PkScript) {
+		scriptType, err := keyScriptType(prevOut.PkScript, privKey, nil)
+		if err != nil {
+			return fmt.Errorf("input %d: %w", i, err)
+		}
+		switch scriptType {
+		case ScriptTypeP2TR:
 			witness, err := txscript.TaprootWitnessSignature(tx, txSigHashes, i, prevOut.Value, prevOut.PkScript, txscript.SigHashDefault, privKey)
 			if err != nil {
 				return err
 			}
 			in.Witness = witness
-		} else if txscript.IsPayToPubKeyHash(prevOut.PkScript) {
+		case ScriptTypeP2PKH:
 			sigScript, err := txscript.SignatureScript(tx, i, prevOut.PkScript, txscript.SigHashAll, privKey, true)
 			if err != nil {
 				return err
 			}
 			in.SignatureScript = sigScript
-		} else {
+		case ScriptTypeP2PK:
+			sig, err := txscript.RawTxInSignature(tx, i, prevOut.PkScript, txscript.SigHashAll, privKey)
+			if err != nil {
+				return err
+			}
+			if in.SignatureScript, err = txscript.NewScriptBuilder().AddData(sig).Script(); err != nil {
+				return err
+			}
+		case ScriptTypeP2WPKH, ScriptTypeP2SHP2WPKH:
 			pubKeyBytes := privKey.PubKey().SerializeCompressed()
 			script, err := PayToPubKeyHashScript(btcutil.Hash160(pubKeyBytes))
 			if err != nil {
@@ -414,7 +637,7 @@ func sign(tx *wire.MsgTx, privateKeys []*btcec.PrivateKey, prevOutFetcher *txscr
 			}
 			in.Witness = witness
 
-			if txscript.IsPayToScriptHash(prevOut.PkScript) {
+			if scriptType == ScriptTypeP2SHP2WPKH {
 				redeemScript, err := PayToWitnessPubKeyHashScript(btcutil.Hash160(pubKeyBytes))
 				if err != nil {
 					return err
@@ -454,11 +677,13 @@ func (tool *InscriptionTool) calculateFee() (int64, []int64) {
 	revealTxFees := make([]int64, 0)
 	for _, tx := range tool.RevealTx {
 		revealTxFee := int64(0)
-		for i, in := range tx.TxIn {
+		for _, in := range tx.TxIn {
 			revealTxFee += tool.RevealTxPrevOutputFetcher.FetchPrevOutput(in.PreviousOutPoint).Value
-			revealTxFee -= tx.TxOut[i].Value
-			revealTxFees = append(revealTxFees, revealTxFee)
 		}
+		for _, out := range tx.TxOut {
+			revealTxFee -= out.Value
+		}
+		revealTxFees = append(revealTxFees, revealTxFee)
 	}
 	return commitTxFee, revealTxFees
 }
@@ -1,10 +1,14 @@
 package brc20
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
 	"log"
 	"testing"
 
+	"github.com/btcsuite/btcd/blockchain"
+	"github.com/btcsuite/btcd/btcutil"
 	"github.com/btcsuite/btcd/chaincfg"
 )
 
@@ -80,3 +84,120 @@ func TestInscribe(t *testing.T) {
 	txsBytes, _ := json.Marshal(txs)
 	t.Log(string(txsBytes))
 }
+
+func newTestInscriptionRequest() *InscriptionRequest {
+	return &InscriptionRequest{
+		CommitTxPrevOutputList: []*PrevOutput{
+			{
+				TxId:       "fcd1a1c33df653427e20159a799e6c1ba28421fd168fe353a54508c956fb382e",
+				VOut:       0,
+				Amount:     252198,
+				Address:    "2NF33rckfiQTiE5Guk5ufUdwms8PgmtnEdc",
+				PrivateKey: "cPnvkvUYyHcSSS26iD1dkrJdV7k1RoUqJLhn3CYxpo398PdLVE22",
+			},
+			{
+				TxId:       "fcd1a1c33df653427e20159a799e6c1ba28421fd168fe353a54508c956fb382e",
+				VOut:       3,
+				Amount:     796800,
+				Address:    "tb1pklh8lqax5l7m2ycypptv2emc4gata2dy28svnwcp9u32wlkenvsspcvhsr",
+				PrivateKey: "cPnvkvUYyHcSSS26iD1dkrJdV7k1RoUqJLhn3CYxpo398PdLVE22",
+			},
+		},
+		CommitFeeRate: 2,
+		RevealFeeRate: 2,
+		InscriptionDataList: []InscriptionData{
+			{
+				ContentType: "text/plain;charset=utf-8",
+				Body:        []byte(`{"p":"brc-20","op":"mint","tick":"xcvb","amt":"1000"}`),
+				RevealAddr:  "tb1qtsq9c4fje6qsmheql8gajwtrrdrs38kdzeersc",
+			},
+			{
+				ContentType: "text/plain;charset=utf-8",
+				Body:        []byte(`{"p":"brc-20","op":"mint","tick":"xcvb","amt":"1000"}`),
+				RevealAddr:  "tb1pklh8lqax5l7m2ycypptv2emc4gata2dy28svnwcp9u32wlkenvsspcvhsr",
+			},
+		},
+		ChangeAddress: "2NF33rckfiQTiE5Guk5ufUdwms8PgmtnEdc",
+	}
+}
+
+func TestRevealFeeRate(t *testing.T) {
+	network := &chaincfg.TestNet3Params
+
+	for _, feeRate := range []FeeRate{1, 1.5, 3.7} {
+		for _, bodySize := range []int{0, 1, 75, 76, 255, 256, 519, 520, 521, 1040, 4000, 10000, 50000, 100000} {
+			request := newTestInscriptionRequest()
+			request.RevealFeeRate = feeRate
+			request.InscriptionDataList = request.InscriptionDataList[:1]
+			request.InscriptionDataList[0].Body = bytes.Repeat([]byte{'a'}, bodySize)
+
+			txs, err := Inscribe(network, request)
+			if err != nil {
+				t.Fatal(err)
+			}
+			revealTx, _ := newTxFromHex(txs.RevealTxs[0])
+			weight := blockchain.GetTransactionWeight(btcutil.NewTx(revealTx))
+			if fee := feeRate.FeeForWeight(weight); txs.RevealTxFees[0] != fee {
+				t.Errorf("%v sat/vB, body %d bytes: reveal fee %d, want %d for weight %d", feeRate, bodySize, txs.RevealTxFees[0], fee, weight)
+			}
+		}
+	}
+}
+
+func TestInscribeExtraOutputs(t *testing.T) {
+	network := &chaincfg.TestNet3Params
+
+	plain, err := Inscribe(network, newTestInscriptionRequest())
+	if err != nil {
+		t.Fatal(err)
+	}
+	plainCommitTx, _ := newTxFromHex(plain.CommitTx)
+
+	opReturn, err := NullDataOutput([]byte("platform"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	request := newTestInscriptionRequest()
+	request.CommitOutputs = []*TxOutput{
+		{Address: "tb1qtsq9c4fje6qsmheql8gajwtrrdrs38kdzeersc", Amount: 10000},
+		opReturn,
+	}
+	request.InscriptionDataList[1].RevealOutputs = []*TxOutput{
+		{Address: "mouQtmBWDS7JnT65Grj2tPzdSmGKJgRMhE", Amount: 1000},
+	}
+	txs, err := Inscribe(network, request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	commitTx, _ := newTxFromHex(txs.CommitTx)
+	if len(commitTx.TxOut) != 5 || commitTx.TxOut[2].Value != 10000 || !bytes.Equal(commitTx.TxOut[3].PkScript, opReturn.PkScript) {
+		t.Fatalf("unexpected commit outputs %d", len(commitTx.TxOut))
+	}
+	commitWeight := blockchain.GetTransactionWeight(btcutil.NewTx(commitTx))
+	if fee := request.CommitFeeRate.FeeForWeight(commitWeight); txs.CommitTxFee != fee {
+		t.Fatalf("commit fee %d, want %d", txs.CommitTxFee, fee)
+	}
+
+	revealTx, _ := newTxFromHex(txs.RevealTxs[1])
+	if len(revealTx.TxOut) != 2 || revealTx.TxOut[0].Value != DefaultRevealOutValue || revealTx.TxOut[1].Value != 1000 {
+		t.Fatalf("unexpected reveal outputs %d", len(revealTx.TxOut))
+	}
+	revealWeight := blockchain.GetTransactionWeight(btcutil.NewTx(revealTx))
+	if fee := request.RevealFeeRate.FeeForWeight(revealWeight); txs.RevealTxFees[1] != fee {
+		t.Fatalf("reveal fee %d, want %d", txs.RevealTxFees[1], fee)
+	}
+	if commitTx.TxOut[1].Value != DefaultRevealOutValue+1000+txs.RevealTxFees[1] {
+		t.Fatalf("reveal funding output %d does not include the extra reveal output", commitTx.TxOut[1].Value)
+	}
+	change := commitTx.TxOut[4].Value
+	plainChange := plainCommitTx.TxOut[2].Value
+	fundingDiff := commitTx.TxOut[1].Value - plainCommitTx.TxOut[1].Value
+	if plainChange-change != 10000+fundingDiff+txs.CommitTxFee-plain.CommitTxFee {
+		t.Fatalf("change %d, plain change %d", change, plainChange)
+	}
+
+	request.CommitOutputs[0].Amount = 100
+	if _, err := Inscribe(network, request); !errors.Is(err, ErrDustOutput) {
+		t.Fatal(err)
+	}
+}
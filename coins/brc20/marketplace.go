@@ -0,0 +1,243 @@
+package brc20
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/btcutil/psbt"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/mempool"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+)
+
+const (
+	ListingSigHashType = txscript.SigHashSingle | txscript.SigHashAnyOneCanPay
+
+	// ListingPaddingInputs is the number of padding inputs in front of the seller input.
+	// SIGHASH_SINGLE binds the seller input to the output of the same index, and the
+	// payment output comes after the padding and inscription outputs, so the seller
+	// input must be at index 2.
+	ListingPaddingInputs = 2
+)
+
+var (
+	ErrInvalidListing = errors.New("invalid listing")
+	ErrPaddingInputs  = errors.New("wrong number of padding inputs")
+)
+
+type FillListingRequest struct {
+	// Listing is the base64 PSBT returned by CreateListing
+	Listing        string     `json:"listing"`
+	PaddingInputs  []*TxInput `json:"paddingInputs"`
+	FundingInputs  []*TxInput `json:"fundingInputs"`
+	ReceiveAddress string     `json:"receiveAddress"`
+	ChangeAddress  string     `json:"changeAddress"`
+	FeeRate        FeeRate    `json:"feeRate"`
+	// FeeGuard bounds the fee of the completed tx, default DefaultFeeGuard
+	FeeGuard *FeeGuard `json:"feeGuard"`
+}
+
+// CreateListing signs the seller's inscription utxo with SIGHASH_SINGLE|ANYONECANPAY
+// against an output paying price to paymentAddress, and returns the partially signed
+// PSBT in base64. A buyer can then add inputs and outputs around it without
+// invalidating the seller's signature, as long as the seller input and the payment
+// output keep the same index.
+func CreateListing(input *TxInput, price int64, paymentAddress string, network *chaincfg.Params) (string, error) {
+	txHash, err := chainhash.NewHashFromStr(input.TxId)
+	if err != nil {
+		return "", err
+	}
+	prevOut := wire.NewOutPoint(txHash, input.VOut)
+	prevPkScript, err := AddrToPkScript(input.Address, network)
+	if err != nil {
+		return "", err
+	}
+	paymentPkScript, err := AddrToPkScript(paymentAddress, network)
+	if err != nil {
+		return "", err
+	}
+
+	bp, err := psbt.New([]*wire.OutPoint{prevOut}, []*wire.TxOut{wire.NewTxOut(price, paymentPkScript)},
+		txVersion, nLockTime, []uint32{wire.MaxTxInSequenceNum})
+	if err != nil {
+		return "", err
+	}
+	updater, err := psbt.NewUpdater(bp)
+	if err != nil {
+		return "", err
+	}
+	prevOutputFetcher := txscript.NewMultiPrevOutFetcher(map[wire.OutPoint]*wire.TxOut{
+		*prevOut: wire.NewTxOut(input.Amount, prevPkScript),
+	})
+	if err = signInput(updater, 0, input, prevOutputFetcher, ListingSigHashType, network); err != nil {
+		return "", err
+	}
+	return bp.B64Encode()
+}
+
+// FillListing completes a purchase from a listing PSBT. The inputs are the two padding
+// inputs, the seller input and the funding inputs. The outputs are the merged padding
+// output and the inscription output, both paid to the buyer, then the seller payment
+// and the change. The padding output takes up exactly the padding input value, so the
+// seller's inscription lands at offset 0 of the inscription output. The seller
+// signature is verified, the buyer inputs are signed with SIGHASH_ALL and the final
+// transaction is returned.
+func FillListing(request *FillListingRequest, network *chaincfg.Params) (*TransferResult, error) {
+	if err := request.FeeGuard.orDefault().checkFeeRate(request.FeeRate); err != nil {
+		return nil, err
+	}
+	if len(request.PaddingInputs) != ListingPaddingInputs {
+		return nil, fmt.Errorf("%w: got %d, need %d", ErrPaddingInputs, len(request.PaddingInputs), ListingPaddingInputs)
+	}
+	for _, in := range request.FundingInputs {
+		if in.IsPadding {
+			return nil, fmt.Errorf("%w: %s:%d", ErrPaddingUtxo, in.TxId, in.VOut)
+		}
+	}
+	listing, sellerPrevOut, err := decodeListing(request.Listing)
+	if err != nil {
+		return nil, err
+	}
+	sellerTxIn := listing.UnsignedTx.TxIn[0]
+	payment := listing.UnsignedTx.TxOut[0]
+
+	prevOuts := txscript.NewMultiPrevOutFetcher(nil)
+	var outPoints []*wire.OutPoint
+	var inPkScripts [][]byte
+	var nSequences []uint32
+	addInput := func(outPoint *wire.OutPoint, prevOut *wire.TxOut, sequence uint32) {
+		outPoints = append(outPoints, outPoint)
+		inPkScripts = append(inPkScripts, prevOut.PkScript)
+		nSequences = append(nSequences, sequence)
+		prevOuts.AddPrevOut(*outPoint, prevOut)
+	}
+	addBuyerInputs := func(ins []*TxInput) (int64, error) {
+		total := int64(0)
+		for _, in := range ins {
+			txHash, err := chainhash.NewHashFromStr(in.TxId)
+			if err != nil {
+				return 0, err
+			}
+			pkScript, err := AddrToPkScript(in.Address, network)
+			if err != nil {
+				return 0, err
+			}
+			addInput(wire.NewOutPoint(txHash, in.VOut), wire.NewTxOut(in.Amount, pkScript), wire.MaxTxInSequenceNum)
+			total += in.Amount
+		}
+		return total, nil
+	}
+	totalPadding, err := addBuyerInputs(request.PaddingInputs)
+	if err != nil {
+		return nil, err
+	}
+	addInput(&sellerTxIn.PreviousOutPoint, sellerPrevOut, sellerTxIn.Sequence)
+	totalFunding, err := addBuyerInputs(request.FundingInputs)
+	if err != nil {
+		return nil, err
+	}
+	totalIn := totalPadding + sellerPrevOut.Value + totalFunding
+
+	changePkScript, err := AddrToPkScript(request.ChangeAddress, network)
+	if err != nil {
+		return nil, err
+	}
+	receivePkScript, err := AddrToPkScript(request.ReceiveAddress, network)
+	if err != nil {
+		return nil, err
+	}
+	paddingOut := wire.NewTxOut(totalPadding, changePkScript)
+	if mempool.IsDust(paddingOut, mempool.DefaultMinRelayTxFee) {
+		return nil, fmt.Errorf("%w: padding output value %d", ErrDustOutput, totalPadding)
+	}
+	outputs := []*wire.TxOut{paddingOut, wire.NewTxOut(sellerPrevOut.Value, receivePkScript), payment}
+	totalOut := totalPadding + sellerPrevOut.Value + payment.Value
+
+	changeOut := wire.NewTxOut(0, changePkScript)
+	weight, err := estimateTxWeight(inPkScripts, outputs)
+	if err != nil {
+		return nil, err
+	}
+	change := totalIn - totalOut - request.FeeRate.FeeForWeight(weight+int64(changeOut.SerializeSize())*4)
+	changeOut.Value = change
+	if change > 0 && !mempool.IsDust(changeOut, mempool.DefaultMinRelayTxFee) {
+		outputs = append(outputs, changeOut)
+	} else {
+		change = 0
+		if totalIn-totalOut < request.FeeRate.FeeForWeight(weight) {
+			return nil, ErrInsufficientBalance
+		}
+	}
+
+	bp, err := psbt.New(outPoints, outputs, listing.UnsignedTx.Version, listing.UnsignedTx.LockTime, nSequences)
+	if err != nil {
+		return nil, err
+	}
+	bp.Inputs[ListingPaddingInputs] = listing.Inputs[0]
+	updater, err := psbt.NewUpdater(bp)
+	if err != nil {
+		return nil, err
+	}
+	for i, in := range append(append([]*TxInput{}, request.PaddingInputs...), request.FundingInputs...) {
+		index := i
+		if i >= ListingPaddingInputs {
+			index++ // skip the seller input
+		}
+		if err = signInput(updater, index, in, prevOuts, txscript.SigHashAll, network); err != nil {
+			return nil, err
+		}
+	}
+	for i := range bp.Inputs {
+		if err = finalizeInput(bp, i); err != nil {
+			return nil, fmt.Errorf("finalize input %d: %w", i, err)
+		}
+	}
+	tx, err := psbt.Extract(bp)
+	if err != nil {
+		return nil, err
+	}
+	if err = verifyInput(tx, ListingPaddingInputs, prevOuts); err != nil {
+		return nil, fmt.Errorf("%w: seller signature: %v", ErrInvalidListing, err)
+	}
+	if err = request.FeeGuard.orDefault().checkTx("listing", 0, tx, totalIn); err != nil {
+		return nil, err
+	}
+	txHex, err := getTxHex(tx)
+	if err != nil {
+		return nil, err
+	}
+	return &TransferResult{
+		Tx:     txHex,
+		Fee:    totalIn - totalOut - change,
+		VSize:  mempool.GetTxVirtualSize(btcutil.NewTx(tx)),
+		Change: change,
+	}, nil
+}
+
+// decodeListing decodes a listing PSBT and returns it with the seller's spent output.
+func decodeListing(listing string) (*psbt.Packet, *wire.TxOut, error) {
+	bp, err := psbt.NewFromRawBytes(strings.NewReader(listing), true)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", ErrInvalidListing, err)
+	}
+	if len(bp.UnsignedTx.TxIn) != 1 || len(bp.UnsignedTx.TxOut) != 1 {
+		return nil, nil, fmt.Errorf("%w: expected 1 input and 1 output", ErrInvalidListing)
+	}
+	pInput := bp.Inputs[0]
+	if pInput.SighashType != ListingSigHashType {
+		return nil, nil, fmt.Errorf("%w: sighash type %v", ErrInvalidListing, pInput.SighashType)
+	}
+	// the buyer's fee and value checks trust this amount, which the seller supplies
+	sellerPrevOut, err := pInputPrevOut(&pInput, bp.UnsignedTx.TxIn[0].PreviousOutPoint)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: seller %v", ErrInvalidListing, err)
+	}
+	if sellerPrevOut == nil {
+		return nil, nil, fmt.Errorf("%w: missing seller utxo", ErrInvalidListing)
+	}
+	return bp, sellerPrevOut, nil
+}
@@ -0,0 +1,186 @@
+package brc20
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/btcsuite/btcd/btcutil/psbt"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+)
+
+func TestCreateListing(t *testing.T) {
+	network := &chaincfg.TestNet3Params
+
+	for _, address := range []string{
+		"tb1pklh8lqax5l7m2ycypptv2emc4gata2dy28svnwcp9u32wlkenvsspcvhsr",
+		"tb1qtsq9c4fje6qsmheql8gajwtrrdrs38kdzeersc",
+		"2NF33rckfiQTiE5Guk5ufUdwms8PgmtnEdc",
+	} {
+		input := &TxInput{
+			TxId:       "46e3ce050474e6da80760a2a0b062836ff13e2a42962dc1c9b17b8f962444206",
+			VOut:       uint32(0),
+			Amount:     int64(546),
+			Address:    address,
+			PrivateKey: "cPnvkvUYyHcSSS26iD1dkrJdV7k1RoUqJLhn3CYxpo398PdLVE22",
+		}
+		listing, err := CreateListing(input, 100000, "tb1qtsq9c4fje6qsmheql8gajwtrrdrs38kdzeersc", network)
+		if err != nil {
+			t.Fatal(err)
+		}
+		bp, err := psbt.NewFromRawBytes(strings.NewReader(listing), true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(bp.UnsignedTx.TxIn) != 1 || len(bp.UnsignedTx.TxOut) != 1 || bp.UnsignedTx.TxOut[0].Value != 100000 {
+			t.Fatalf("%s: unexpected listing layout", address)
+		}
+		if bp.Inputs[0].SighashType != ListingSigHashType {
+			t.Fatalf("%s: sighash type %v", address, bp.Inputs[0].SighashType)
+		}
+		if err = psbt.Finalize(bp, 0); err != nil {
+			t.Fatal(err)
+		}
+		listingTx, err := psbt.Extract(bp)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sellerTxIn := listingTx.TxIn[0]
+		prevPkScript, _ := AddrToPkScript(address, network)
+
+		// the signature stays valid with the seller input and payment output moved to index 2
+		tx := wire.NewMsgTx(txVersion)
+		prevOuts := txscript.NewMultiPrevOutFetcher(nil)
+		for i := 0; i < 2; i++ {
+			outPoint := wire.OutPoint{Hash: chainhash.Hash{byte(i + 1)}, Index: uint32(i)}
+			tx.AddTxIn(wire.NewTxIn(&outPoint, nil, nil))
+			prevOuts.AddPrevOut(outPoint, wire.NewTxOut(600, prevPkScript))
+			tx.AddTxOut(wire.NewTxOut(600, prevPkScript))
+		}
+		tx.AddTxIn(sellerTxIn)
+		prevOuts.AddPrevOut(sellerTxIn.PreviousOutPoint, wire.NewTxOut(input.Amount, prevPkScript))
+		tx.AddTxOut(listingTx.TxOut[0])
+
+		engine, err := txscript.NewEngine(prevPkScript, tx, 2, txscript.StandardVerifyFlags, nil,
+			txscript.NewTxSigHashes(tx, prevOuts), input.Amount, prevOuts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = engine.Execute(); err != nil {
+			t.Fatalf("%s: %v", address, err)
+		}
+	}
+}
+
+func TestFillListing(t *testing.T) {
+	network := &chaincfg.TestNet3Params
+
+	seller := &TxInput{
+		TxId:       "46e3ce050474e6da80760a2a0b062836ff13e2a42962dc1c9b17b8f962444206",
+		VOut:       uint32(0),
+		Amount:     int64(546),
+		Address:    "tb1pklh8lqax5l7m2ycypptv2emc4gata2dy28svnwcp9u32wlkenvsspcvhsr",
+		PrivateKey: "cPnvkvUYyHcSSS26iD1dkrJdV7k1RoUqJLhn3CYxpo398PdLVE22",
+	}
+	listing, err := CreateListing(seller, 100000, "2NF33rckfiQTiE5Guk5ufUdwms8PgmtnEdc", network)
+	if err != nil {
+		t.Fatal(err)
+	}
+	request := &FillListingRequest{
+		Listing: listing,
+		PaddingInputs: []*TxInput{
+			{
+				TxId:       "d1696c10046ec8b2d938924f1923f1f2e1588095fbf3ea0f8cd640b51da51ba2",
+				VOut:       uint32(0),
+				Amount:     int64(600),
+				Address:    "tb1qtsq9c4fje6qsmheql8gajwtrrdrs38kdzeersc",
+				PrivateKey: "cPnvkvUYyHcSSS26iD1dkrJdV7k1RoUqJLhn3CYxpo398PdLVE22",
+			},
+			{
+				TxId:       "d1696c10046ec8b2d938924f1923f1f2e1588095fbf3ea0f8cd640b51da51ba2",
+				VOut:       uint32(1),
+				Amount:     int64(600),
+				Address:    "tb1qtsq9c4fje6qsmheql8gajwtrrdrs38kdzeersc",
+				PrivateKey: "cPnvkvUYyHcSSS26iD1dkrJdV7k1RoUqJLhn3CYxpo398PdLVE22",
+			},
+		},
+		FundingInputs: []*TxInput{
+			{
+				TxId:       "25b9d08a26c8d47795301dd47a861cff0459d14f27fbd41cffaca17d9aa20f87",
+				VOut:       uint32(0),
+				Amount:     int64(249352),
+				Address:    "tb1pklh8lqax5l7m2ycypptv2emc4gata2dy28svnwcp9u32wlkenvsspcvhsr",
+				PrivateKey: "cPnvkvUYyHcSSS26iD1dkrJdV7k1RoUqJLhn3CYxpo398PdLVE22",
+			},
+		},
+		ReceiveAddress: "tb1pklh8lqax5l7m2ycypptv2emc4gata2dy28svnwcp9u32wlkenvsspcvhsr",
+		ChangeAddress:  "tb1qtsq9c4fje6qsmheql8gajwtrrdrs38kdzeersc",
+		FeeRate:        3,
+	}
+	result, err := FillListing(request, network)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx, err := newTxFromHex(result.Tx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tx.TxIn) != 4 || len(tx.TxOut) != 4 {
+		t.Fatalf("unexpected layout: %d inputs, %d outputs", len(tx.TxIn), len(tx.TxOut))
+	}
+	if tx.TxIn[2].PreviousOutPoint.Hash.String() != seller.TxId {
+		t.Fatal("seller input must be at index 2")
+	}
+	if tx.TxOut[0].Value != 1200 || tx.TxOut[1].Value != seller.Amount || tx.TxOut[2].Value != 100000 {
+		t.Fatalf("unexpected output values %d %d %d", tx.TxOut[0].Value, tx.TxOut[1].Value, tx.TxOut[2].Value)
+	}
+	if result.Fee < request.FeeRate.FeeForVSize(result.VSize) {
+		t.Fatalf("fee %d below %v sat/vB for %d vB", result.Fee, request.FeeRate, result.VSize)
+	}
+
+	prevOuts := txscript.NewMultiPrevOutFetcher(nil)
+	for i, in := range []*TxInput{request.PaddingInputs[0], request.PaddingInputs[1], seller, request.FundingInputs[0]} {
+		pkScript, _ := AddrToPkScript(in.Address, network)
+		prevOuts.AddPrevOut(tx.TxIn[i].PreviousOutPoint, wire.NewTxOut(in.Amount, pkScript))
+	}
+	for i := range tx.TxIn {
+		if err := verifyInput(tx, i, prevOuts); err != nil {
+			t.Fatalf("input %d: %v", i, err)
+		}
+	}
+
+	for feeRate, want := range map[FeeRate]error{0: ErrInvalidFeeRate, -1: ErrInvalidFeeRate, MaxFeeRate + 1: ErrAbsurdFeeRate} {
+		badRate := *request
+		badRate.FeeRate = feeRate
+		if _, err := FillListing(&badRate, network); !errors.Is(err, want) {
+			t.Fatalf("fee rate %v: expected %v, got %v", feeRate, want, err)
+		}
+	}
+
+	// a listing with a tampered price fails the seller signature check
+	bp, _ := psbt.NewFromRawBytes(strings.NewReader(listing), true)
+	bp.UnsignedTx.TxOut[0].Value = 1000
+	request.Listing, _ = bp.B64Encode()
+	if _, err := FillListing(request, network); !errors.Is(err, ErrInvalidListing) {
+		t.Fatal(err)
+	}
+	// a non-witness seller utxo must be the tx the listing spends
+	bp, _ = psbt.NewFromRawBytes(strings.NewReader(listing), true)
+	forged := wire.NewMsgTx(txVersion)
+	forged.AddTxIn(wire.NewTxIn(&wire.OutPoint{Hash: chainhash.Hash{9}}, nil, nil))
+	forged.AddTxOut(bp.Inputs[0].WitnessUtxo)
+	bp.Inputs[0].WitnessUtxo, bp.Inputs[0].NonWitnessUtxo = nil, forged
+	request.Listing, _ = bp.B64Encode()
+	if _, err := FillListing(request, network); !errors.Is(err, ErrInvalidListing) ||
+		!strings.Contains(err.Error(), ErrUtxoMismatch.Error()) {
+		t.Fatalf("expected a seller utxo mismatch, got %v", err)
+	}
+	request.Listing = listing
+	request.PaddingInputs = request.PaddingInputs[:1]
+	if _, err := FillListing(request, network); !errors.Is(err, ErrPaddingInputs) {
+		t.Fatal(err)
+	}
+}
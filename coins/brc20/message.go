@@ -0,0 +1,265 @@
+package brc20
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+
+	"github.com/btcsuite/btcd/btcec/v2/ecdsa"
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/btcutil/psbt"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+)
+
+const (
+	bip322Tag          = "BIP0322-signed-message"
+	signedMessageMagic = "Bitcoin Signed Message:\n"
+)
+
+var ErrInvalidMessageSignature = errors.New("invalid message signature")
+
+// SignMessageBIP322 returns the base64 BIP-322 simple signature of message by the
+// key of address, the witness of the to_sign transaction. Only segwit and taproot
+// addresses have one; the others sign with SignMessageBIP322Full.
+func SignMessageBIP322(message, privateKey, address string, network *chaincfg.Params) (string, error) {
+	toSign, err := signBIP322(message, privateKey, address, network)
+	if err != nil {
+		return "", err
+	}
+	if len(toSign.TxIn[0].SignatureScript) > 0 {
+		return "", fmt.Errorf("%w: %s has no simple signature, use SignMessageBIP322Full", ErrUnsupportedScript, address)
+	}
+	var buf bytes.Buffer
+	if err = writeWitness(&buf, toSign.TxIn[0].Witness); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(buf.Bytes()), nil
+}
+
+// SignMessageBIP322Full returns the base64 BIP-322 full signature of message by the
+// key of address, the whole to_sign transaction.
+func SignMessageBIP322Full(message, privateKey, address string, network *chaincfg.Params) (string, error) {
+	toSign, err := signBIP322(message, privateKey, address, network)
+	if err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	if err = toSign.Serialize(&buf); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(buf.Bytes()), nil
+}
+
+// VerifyMessageBIP322 checks a BIP-322 simple or full signature of message by
+// address. For p2pkh addresses a legacy signmessage signature is accepted too.
+func VerifyMessageBIP322(message, signature, address string, network *chaincfg.Params) error {
+	sig, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidMessageSignature, err)
+	}
+	pkScript, err := AddrToPkScript(address, network)
+	if err != nil {
+		return err
+	}
+	toSpend, err := bip322ToSpend(message, pkScript)
+	if err != nil {
+		return err
+	}
+
+	toSign := new(wire.MsgTx)
+	reader := bytes.NewReader(sig)
+	if err = toSign.Deserialize(reader); err != nil || reader.Len() > 0 || !isBIP322ToSign(toSign, toSpend) {
+		if txscript.IsPayToPubKeyHash(pkScript) && len(sig) == 65 {
+			return VerifyMessage(message, signature, address, network)
+		}
+		witness, err := readWitness(sig)
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidMessageSignature, err)
+		}
+		toSign = bip322ToSign(toSpend)
+		toSign.TxIn[0].Witness = witness
+	}
+
+	prevOuts := txscript.NewMultiPrevOutFetcher(nil)
+	prevOuts.AddPrevOut(toSign.TxIn[0].PreviousOutPoint, toSpend.TxOut[0])
+	if err = verifyInput(toSign, 0, prevOuts); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidMessageSignature, err)
+	}
+	return nil
+}
+
+// SignMessage returns the base64 legacy signmessage signature of message, which
+// VerifyMessage checks against the p2pkh address of privateKey.
+func SignMessage(message, privateKey string) (string, error) {
+	wif, err := btcutil.DecodeWIF(privateKey)
+	if err != nil {
+		return "", err
+	}
+	hash, err := signedMessageHash(message)
+	if err != nil {
+		return "", err
+	}
+	sig, err := ecdsa.SignCompact(wif.PrivKey, hash, wif.CompressPubKey)
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(sig), nil
+}
+
+// VerifyMessage checks a legacy signmessage signature of message by a p2pkh address.
+func VerifyMessage(message, signature, address string, network *chaincfg.Params) error {
+	pkScript, err := AddrToPkScript(address, network)
+	if err != nil {
+		return err
+	}
+	if !txscript.IsPayToPubKeyHash(pkScript) {
+		return fmt.Errorf("%w: signmessage only signs for p2pkh, use BIP-322 for %s", ErrUnsupportedScript, address)
+	}
+	sig, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidMessageSignature, err)
+	}
+	hash, err := signedMessageHash(message)
+	if err != nil {
+		return err
+	}
+	pubKey, compressed, err := ecdsa.RecoverCompact(sig, hash)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidMessageSignature, err)
+	}
+	pubKeyBytes := pubKey.SerializeUncompressed()
+	if compressed {
+		pubKeyBytes = pubKey.SerializeCompressed()
+	}
+	if !bytes.Equal(pkScript[3:23], btcutil.Hash160(pubKeyBytes)) {
+		return fmt.Errorf("%w: not signed by %s", ErrInvalidMessageSignature, address)
+	}
+	return nil
+}
+
+// signBIP322 returns the to_sign transaction of message, signed by privateKey for
+// address through a PSBT like Transfer inputs.
+func signBIP322(message, privateKey, address string, network *chaincfg.Params) (*wire.MsgTx, error) {
+	wif, err := btcutil.DecodeWIF(privateKey)
+	if err != nil {
+		return nil, err
+	}
+	pkScript, err := AddrToPkScript(address, network)
+	if err != nil {
+		return nil, err
+	}
+	toSpend, err := bip322ToSpend(message, pkScript)
+	if err != nil {
+		return nil, err
+	}
+	bp, err := psbt.NewFromUnsignedTx(bip322ToSign(toSpend))
+	if err != nil {
+		return nil, err
+	}
+	updater, err := psbt.NewUpdater(bp)
+	if err != nil {
+		return nil, err
+	}
+	if txscript.IsPayToPubKeyHash(pkScript) || txscript.IsPayToPubKey(pkScript) {
+		err = updater.AddInNonWitnessUtxo(toSpend, 0)
+	} else {
+		err = updater.AddInWitnessUtxo(toSpend.TxOut[0], 0)
+	}
+	if err != nil {
+		return nil, err
+	}
+	prevOuts := txscript.NewMultiPrevOutFetcher(nil)
+	prevOuts.AddPrevOut(bp.UnsignedTx.TxIn[0].PreviousOutPoint, toSpend.TxOut[0])
+	if err = signPInput(updater, 0, wif.PrivKey, pkScript, 0, prevOuts, txscript.SigHashAll, nil); err != nil {
+		return nil, err
+	}
+	if err = finalizeInput(bp, 0); err != nil {
+		return nil, err
+	}
+	return psbt.Extract(bp)
+}
+
+// bip322ToSpend returns the virtual transaction paying to pkScript that commits to
+// message.
+func bip322ToSpend(message string, pkScript []byte) (*wire.MsgTx, error) {
+	messageHash := chainhash.TaggedHash([]byte(bip322Tag), []byte(message))
+	sigScript, err := txscript.NewScriptBuilder().AddOp(txscript.OP_0).AddData(messageHash[:]).Script()
+	if err != nil {
+		return nil, err
+	}
+	toSpend := wire.NewMsgTx(0)
+	toSpend.AddTxIn(&wire.TxIn{
+		PreviousOutPoint: wire.OutPoint{Index: wire.MaxPrevOutIndex},
+		SignatureScript:  sigScript,
+		Sequence:         0,
+	})
+	toSpend.AddTxOut(wire.NewTxOut(0, pkScript))
+	return toSpend, nil
+}
+
+// bip322ToSign returns the unsigned virtual transaction spending toSpend.
+func bip322ToSign(toSpend *wire.MsgTx) *wire.MsgTx {
+	toSign := wire.NewMsgTx(0)
+	toSign.AddTxIn(&wire.TxIn{
+		PreviousOutPoint: wire.OutPoint{Hash: toSpend.TxHash()},
+		Sequence:         0,
+	})
+	toSign.AddTxOut(wire.NewTxOut(0, []byte{txscript.OP_RETURN}))
+	return toSign
+}
+
+// isBIP322ToSign reports whether tx is a full format to_sign transaction of toSpend.
+// The version, lock time and sequence are the signer's.
+func isBIP322ToSign(tx, toSpend *wire.MsgTx) bool {
+	return len(tx.TxIn) == 1 && len(tx.TxOut) == 1 &&
+		tx.TxIn[0].PreviousOutPoint == (wire.OutPoint{Hash: toSpend.TxHash()}) &&
+		tx.TxOut[0].Value == 0 && bytes.Equal(tx.TxOut[0].PkScript, []byte{txscript.OP_RETURN})
+}
+
+func signedMessageHash(message string) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := wire.WriteVarString(&buf, 0, signedMessageMagic); err != nil {
+		return nil, err
+	}
+	if err := wire.WriteVarString(&buf, 0, message); err != nil {
+		return nil, err
+	}
+	return chainhash.DoubleHashB(buf.Bytes()), nil
+}
+
+func writeWitness(buf *bytes.Buffer, witness wire.TxWitness) error {
+	if err := wire.WriteVarInt(buf, 0, uint64(len(witness))); err != nil {
+		return err
+	}
+	for _, item := range witness {
+		if err := wire.WriteVarBytes(buf, 0, item); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func readWitness(b []byte) (wire.TxWitness, error) {
+	reader := bytes.NewReader(b)
+	count, err := wire.ReadVarInt(reader, 0)
+	if err != nil {
+		return nil, err
+	}
+	if count > uint64(len(b)) {
+		return nil, fmt.Errorf("%d witness items in %d bytes", count, len(b))
+	}
+	witness := make(wire.TxWitness, count)
+	for i := range witness {
+		if witness[i], err = wire.ReadVarBytes(reader, 0, uint32(len(b)), "witness item"); err != nil {
+			return nil, err
+		}
+	}
+	if reader.Len() > 0 {
+		return nil, fmt.Errorf("%d bytes after the witness", reader.Len())
+	}
+	return witness, nil
+}
@@ -0,0 +1,116 @@
+package brc20
+
+import (
+	"encoding/hex"
+	"errors"
+	"testing"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+)
+
+func TestVerifyMessageBIP322Vectors(t *testing.T) {
+	network := &chaincfg.MainNetParams
+	if hash := chainhash.TaggedHash([]byte(bip322Tag), []byte("Hello World")); hex.EncodeToString(hash[:]) !=
+		"f0eb03b1a75ac6d9847f55c624a99169b5dccba2a31f5b23bea77ba270de0a7a" {
+		t.Fatalf("unexpected message hash %x", hash[:])
+	}
+
+	// test vectors of BIP-322
+	tests := []struct {
+		address   string
+		message   string
+		signature string
+	}{
+		{
+			address:   "bc1q9vza2e8x573nczrlzms0wvx3gsqjx7vavgkx0l",
+			message:   "",
+			signature: "AkcwRAIgM2gBAQqvZX15ZiysmKmQpDrG83avLIT492QBzLnQIxYCIBaTpOaD20qRlEylyxFSeEA2ba9YOixpX8z46TSDtS40ASECx/EgAxlkQpQ9hYjgGu6EBCPMVPwVIVJqO4XCsMvViHI=",
+		},
+		{
+			address:   "bc1q9vza2e8x573nczrlzms0wvx3gsqjx7vavgkx0l",
+			message:   "Hello World",
+			signature: "AkcwRAIgZRfIY3p7/DoVTty6YZbWS71bc5Vct9p9Fia83eRmw2QCICK/ENGfwLtptFluMGs2KsqoNSk89pO7F29zJLUx9a/sASECx/EgAxlkQpQ9hYjgGu6EBCPMVPwVIVJqO4XCsMvViHI=",
+		},
+		{
+			address:   "bc1ppv609nr0vr25u07u95waq5lucwfm6tde4nydujnu8npg4q75mr5sxq8lt3",
+			message:   "Hello World",
+			signature: "AUHd69PrJQEv+oKTfZ8l+WROBHuy9HKrbFCJu7U1iK2iiEy1vMU5EfMtjc+VSHM7aU0SDbak5IUZRVno2P5mjSafAQ==",
+		},
+	}
+	for _, test := range tests {
+		if err := VerifyMessageBIP322(test.message, test.signature, test.address, network); err != nil {
+			t.Errorf("%s %q: %v", test.address, test.message, err)
+		}
+		if err := VerifyMessageBIP322(test.message+"!", test.signature, test.address, network); !errors.Is(err, ErrInvalidMessageSignature) {
+			t.Errorf("%s: expected ErrInvalidMessageSignature, got %v", test.address, err)
+		}
+	}
+}
+
+func TestSignMessageBIP322(t *testing.T) {
+	network := &chaincfg.TestNet3Params
+	privateKey := "cPnvkvUYyHcSSS26iD1dkrJdV7k1RoUqJLhn3CYxpo398PdLVE22"
+	message := "I own this address"
+	for _, address := range []string{
+		"tb1pklh8lqax5l7m2ycypptv2emc4gata2dy28svnwcp9u32wlkenvsspcvhsr",
+		"tb1qtsq9c4fje6qsmheql8gajwtrrdrs38kdzeersc",
+		"2NF33rckfiQTiE5Guk5ufUdwms8PgmtnEdc",
+		"mouQtmBWDS7JnT65Grj2tPzdSmGKJgRMhE",
+	} {
+		full, err := SignMessageBIP322Full(message, privateKey, address, network)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = VerifyMessageBIP322(message, full, address, network); err != nil {
+			t.Errorf("%s full: %v", address, err)
+		}
+
+		simple, err := SignMessageBIP322(message, privateKey, address, network)
+		if address == "2NF33rckfiQTiE5Guk5ufUdwms8PgmtnEdc" || address == "mouQtmBWDS7JnT65Grj2tPzdSmGKJgRMhE" {
+			if !errors.Is(err, ErrUnsupportedScript) {
+				t.Errorf("%s simple: expected ErrUnsupportedScript, got %v", address, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = VerifyMessageBIP322(message, simple, address, network); err != nil {
+			t.Errorf("%s simple: %v", address, err)
+		}
+	}
+
+	full, err := SignMessageBIP322Full(message, privateKey, "tb1qtsq9c4fje6qsmheql8gajwtrrdrs38kdzeersc", network)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = VerifyMessageBIP322(message, full, "tb1pklh8lqax5l7m2ycypptv2emc4gata2dy28svnwcp9u32wlkenvsspcvhsr", network); !errors.Is(err, ErrInvalidMessageSignature) {
+		t.Errorf("expected ErrInvalidMessageSignature for another address, got %v", err)
+	}
+	if _, err = SignMessageBIP322(message, newTestMultisig(t).wifs[1], "tb1qtsq9c4fje6qsmheql8gajwtrrdrs38kdzeersc", network); !errors.Is(err, ErrKeyMismatch) {
+		t.Errorf("expected ErrKeyMismatch, got %v", err)
+	}
+}
+
+func TestSignMessage(t *testing.T) {
+	network := &chaincfg.TestNet3Params
+	message := "I own this address"
+	signature, err := SignMessage(message, "cPnvkvUYyHcSSS26iD1dkrJdV7k1RoUqJLhn3CYxpo398PdLVE22")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = VerifyMessage(message, signature, "mouQtmBWDS7JnT65Grj2tPzdSmGKJgRMhE", network); err != nil {
+		t.Fatal(err)
+	}
+	// BIP-322 verification accepts legacy signatures for p2pkh
+	if err = VerifyMessageBIP322(message, signature, "mouQtmBWDS7JnT65Grj2tPzdSmGKJgRMhE", network); err != nil {
+		t.Fatal(err)
+	}
+	if err = VerifyMessage(message+"!", signature, "mouQtmBWDS7JnT65Grj2tPzdSmGKJgRMhE", network); !errors.Is(err, ErrInvalidMessageSignature) {
+		t.Errorf("expected ErrInvalidMessageSignature, got %v", err)
+	}
+	if err = VerifyMessage(message, signature, "tb1qtsq9c4fje6qsmheql8gajwtrrdrs38kdzeersc", network); !errors.Is(err, ErrUnsupportedScript) {
+		t.Errorf("expected ErrUnsupportedScript, got %v", err)
+	}
+}
@@ -0,0 +1,278 @@
+package brc20
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/btcutil/psbt"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+)
+
+var (
+	ErrInvalidMultisig   = errors.New("invalid multisig")
+	ErrMultisigThreshold = errors.New("not enough multisig keys")
+)
+
+// multisigInput is an input spending a p2sh, p2sh-p2wsh or p2wsh multisig output.
+type multisigInput struct {
+	pkScript []byte
+	// redeemScript is the multisig script for p2sh, the witness program for p2sh-p2wsh
+	redeemScript  []byte
+	witnessScript []byte
+	pubKeys       [][]byte
+	threshold     int
+	privateKeys   []*btcec.PrivateKey
+}
+
+// newMultisigInput checks that the multisig script, witnessScript if set and
+// redeemScript otherwise, is the one pkScript pays to.
+func newMultisigInput(pkScript, redeemScript, witnessScript []byte, privateKeys []*btcec.PrivateKey) (*multisigInput, error) {
+	in := &multisigInput{pkScript: pkScript, witnessScript: witnessScript}
+	script := witnessScript
+	switch {
+	case len(witnessScript) > 0:
+		witnessScriptHash := sha256.Sum256(witnessScript)
+		program, err := txscript.NewScriptBuilder().AddOp(txscript.OP_0).AddData(witnessScriptHash[:]).Script()
+		if err != nil {
+			return nil, err
+		}
+		if txscript.IsPayToScriptHash(pkScript) {
+			in.redeemScript = program
+			program, err = payToScriptHashScript(program)
+			if err != nil {
+				return nil, err
+			}
+		}
+		if !bytes.Equal(program, pkScript) {
+			return nil, fmt.Errorf("%w: the output does not pay to the witness script", ErrInvalidMultisig)
+		}
+	case len(redeemScript) > 0:
+		script = redeemScript
+		in.redeemScript = redeemScript
+		p2sh, err := payToScriptHashScript(redeemScript)
+		if err != nil {
+			return nil, err
+		}
+		if !bytes.Equal(p2sh, pkScript) {
+			return nil, fmt.Errorf("%w: the output does not pay to the redeem script", ErrInvalidMultisig)
+		}
+	default:
+		return nil, fmt.Errorf("%w: missing script", ErrInvalidMultisig)
+	}
+	if isMultisig, err := txscript.IsMultisigScript(script); err != nil || !isMultisig {
+		return nil, fmt.Errorf("%w: not a multisig script", ErrInvalidMultisig)
+	}
+	_, threshold, err := txscript.CalcMultiSigStats(script)
+	if err != nil {
+		return nil, err
+	}
+	if in.pubKeys, err = txscript.PushedData(script); err != nil {
+		return nil, err
+	}
+	in.threshold = threshold
+	for _, privateKey := range privateKeys {
+		if in.keyIndex(privateKey) < 0 {
+			return nil, fmt.Errorf("%w: key %x is not in the script", ErrInvalidMultisig,
+				privateKey.PubKey().SerializeCompressed())
+		}
+	}
+	in.privateKeys = privateKeys
+	return in, nil
+}
+
+// multisig returns the multisig of in, nil for a single-key input.
+func (in *TxInput) multisig(pkScript []byte) (*multisigInput, error) {
+	if len(in.RedeemScript) == 0 && len(in.WitnessScript) == 0 {
+		return nil, nil
+	}
+	privateKeys, err := decodePrivateKeys(in.PrivateKey, in.PrivateKeys)
+	if err != nil {
+		return nil, err
+	}
+	return newMultisigInput(pkScript, in.RedeemScript, in.WitnessScript, privateKeys)
+}
+
+// multisig returns the multisig of prevOutput, nil for a single-key output.
+func (prevOutput *PrevOutput) multisig(pkScript []byte) (*multisigInput, error) {
+	if prevOutput.RedeemScript == "" && prevOutput.WitnessScript == "" {
+		return nil, nil
+	}
+	redeemScript, err := hex.DecodeString(prevOutput.RedeemScript)
+	if err != nil {
+		return nil, err
+	}
+	witnessScript, err := hex.DecodeString(prevOutput.WitnessScript)
+	if err != nil {
+		return nil, err
+	}
+	privateKeys, err := decodePrivateKeys(prevOutput.PrivateKey, prevOutput.PrivateKeys)
+	if err != nil {
+		return nil, err
+	}
+	return newMultisigInput(pkScript, redeemScript, witnessScript, privateKeys)
+}
+
+// keys returns the private key of prevOutput, or its multisig. The other one is nil.
+func (prevOutput *PrevOutput) keys(network *chaincfg.Params) (*btcec.PrivateKey, *multisigInput, error) {
+	pkScript, err := AddrToPkScript(prevOutput.Address, network)
+	if err != nil {
+		return nil, nil, err
+	}
+	multisig, err := prevOutput.multisig(pkScript)
+	if err != nil || multisig != nil {
+		return nil, multisig, err
+	}
+	wif, err := btcutil.DecodeWIF(prevOutput.PrivateKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	return wif.PrivKey, nil, nil
+}
+
+func decodePrivateKeys(privateKey string, privateKeys []string) ([]*btcec.PrivateKey, error) {
+	var keys []*btcec.PrivateKey
+	if privateKey != "" {
+		privateKeys = append([]string{privateKey}, privateKeys...)
+	}
+	for _, key := range privateKeys {
+		wif, err := btcutil.DecodeWIF(key)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, wif.PrivKey)
+	}
+	return keys, nil
+}
+
+func (in *multisigInput) keyIndex(privateKey *btcec.PrivateKey) int {
+	pubKey := privateKey.PubKey()
+	for i, key := range in.pubKeys {
+		if bytes.Equal(key, pubKey.SerializeCompressed()) || bytes.Equal(key, pubKey.SerializeUncompressed()) {
+			return i
+		}
+	}
+	return -1
+}
+
+// signatures returns the signature of every key, by the index of its public key in
+// the script.
+func (in *multisigInput) signatures(tx *wire.MsgTx, index int, prevOutFetcher txscript.PrevOutputFetcher,
+	hashType txscript.SigHashType) (map[int][]byte, error) {
+	sigs := make(map[int][]byte)
+	sigHashes := txscript.NewTxSigHashes(tx, prevOutFetcher)
+	amount := prevOutFetcher.FetchPrevOutput(tx.TxIn[index].PreviousOutPoint).Value
+	for _, privateKey := range in.privateKeys {
+		var sig []byte
+		var err error
+		if len(in.witnessScript) > 0 {
+			sig, err = txscript.RawTxInWitnessSignature(tx, sigHashes, index, amount, in.witnessScript, hashType, privateKey)
+		} else {
+			sig, err = txscript.RawTxInSignature(tx, index, in.redeemScript, hashType, privateKey)
+		}
+		if err != nil {
+			return nil, err
+		}
+		sigs[in.keyIndex(privateKey)] = sig
+	}
+	return sigs, nil
+}
+
+// signTx sets the final scriptSig and witness of input index of tx, with the
+// signatures in script key order.
+func (in *multisigInput) signTx(tx *wire.MsgTx, index int, prevOutFetcher txscript.PrevOutputFetcher) error {
+	sigs, err := in.signatures(tx, index, prevOutFetcher, txscript.SigHashAll)
+	if err != nil {
+		return err
+	}
+	if len(sigs) < in.threshold {
+		return fmt.Errorf("%w: input %d has %d of %d", ErrMultisigThreshold, index, len(sigs), in.threshold)
+	}
+	// OP_CHECKMULTISIG pops one element too many
+	stack := [][]byte{nil}
+	for i := range in.pubKeys {
+		if sig, ok := sigs[i]; ok && len(stack) <= in.threshold {
+			stack = append(stack, sig)
+		}
+	}
+	txIn := tx.TxIn[index]
+	if len(in.witnessScript) == 0 {
+		builder := txscript.NewScriptBuilder().AddOp(txscript.OP_0)
+		for _, sig := range stack[1:] {
+			builder.AddData(sig)
+		}
+		txIn.SignatureScript, err = builder.AddData(in.redeemScript).Script()
+		return err
+	}
+	txIn.Witness = append(stack, in.witnessScript)
+	if len(in.redeemScript) > 0 {
+		txIn.SignatureScript, err = txscript.NewScriptBuilder().AddData(in.redeemScript).Script()
+	}
+	return err
+}
+
+// signPSBT adds the partial signatures of the keys to input index of the PSBT, up
+// to the threshold. The finalizer puts them in script key order.
+func (in *multisigInput) signPSBT(updater *psbt.Updater, index int, prevOutFetcher txscript.PrevOutputFetcher,
+	hashType txscript.SigHashType) error {
+	sigs, err := in.signatures(updater.Upsbt.UnsignedTx, index, prevOutFetcher, hashType)
+	if err != nil {
+		return err
+	}
+	var witnessScript []byte
+	if len(in.witnessScript) > 0 {
+		witnessScript = in.witnessScript
+	}
+	for i := range in.pubKeys {
+		sig, ok := sigs[i]
+		pInput := updater.Upsbt.Inputs[index]
+		// the finalizer puts every partial signature in the witness
+		if !ok || hasPartialSigFor(pInput, in.pubKeys[i]) || len(pInput.PartialSigs) >= in.threshold {
+			continue
+		}
+		if _, err = updater.Sign(index, sig, in.pubKeys[i], in.redeemScript, witnessScript); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// estimateWeight estimates the weight of spending the input with threshold signatures.
+func (in *multisigInput) estimateWeight() int64 {
+	sigsSize := int64(in.threshold) * (1 + 73)
+	if len(in.witnessScript) == 0 {
+		scriptSigSize := 1 + sigsSize + pushSize(in.redeemScript)
+		return (32 + 4 + int64(wire.VarIntSerializeSize(uint64(scriptSigSize))) + scriptSigSize + 4) * 4
+	}
+	scriptSigSize := int64(0)
+	if len(in.redeemScript) > 0 {
+		scriptSigSize = pushSize(in.redeemScript)
+	}
+	witnessSize := int64(1+1+wire.VarIntSerializeSize(uint64(len(in.witnessScript)))+len(in.witnessScript)) + sigsSize
+	return (32+4+1+scriptSigSize+4)*4 + witnessSize
+}
+
+func pushSize(data []byte) int64 {
+	switch {
+	case len(data) < txscript.OP_PUSHDATA1:
+		return int64(1 + len(data))
+	case len(data) <= 0xff:
+		return int64(2 + len(data))
+	default:
+		return int64(3 + len(data))
+	}
+}
+
+func payToScriptHashScript(script []byte) ([]byte, error) {
+	return txscript.NewScriptBuilder().
+		AddOp(txscript.OP_HASH160).
+		AddData(btcutil.Hash160(script)).
+		AddOp(txscript.OP_EQUAL).
+		Script()
+}
@@ -0,0 +1,246 @@
+package brc20
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"testing"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+)
+
+type testMultisig struct {
+	wifs          []string
+	script        []byte
+	p2wsh         string
+	p2shP2wsh     string
+	p2sh          string
+	p2shPrevTxHex string
+	p2shPrevTxId  string
+}
+
+// newTestMultisig returns a 2-of-3 multisig of the test key and two fixed keys.
+func newTestMultisig(t *testing.T) *testMultisig {
+	network := &chaincfg.TestNet3Params
+	ms := &testMultisig{wifs: []string{"cPnvkvUYyHcSSS26iD1dkrJdV7k1RoUqJLhn3CYxpo398PdLVE22"}}
+	for _, b := range []byte{2, 3} {
+		privateKey, _ := btcec.PrivKeyFromBytes(chainhash.HashB([]byte{b}))
+		wif, err := btcutil.NewWIF(privateKey, network, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ms.wifs = append(ms.wifs, wif.String())
+	}
+	var pubKeys []*btcutil.AddressPubKey
+	for _, wifStr := range ms.wifs {
+		wif, _ := btcutil.DecodeWIF(wifStr)
+		pubKey, err := btcutil.NewAddressPubKey(wif.PrivKey.PubKey().SerializeCompressed(), network)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pubKeys = append(pubKeys, pubKey)
+	}
+	var err error
+	if ms.script, err = txscript.MultiSigScript(pubKeys, 2); err != nil {
+		t.Fatal(err)
+	}
+	scriptHash := sha256.Sum256(ms.script)
+	p2wsh, _ := btcutil.NewAddressWitnessScriptHash(scriptHash[:], network)
+	ms.p2wsh = p2wsh.EncodeAddress()
+	program, _ := txscript.PayToAddrScript(p2wsh)
+	p2shP2wsh, _ := btcutil.NewAddressScriptHash(program, network)
+	ms.p2shP2wsh = p2shP2wsh.EncodeAddress()
+	p2sh, _ := btcutil.NewAddressScriptHash(ms.script, network)
+	ms.p2sh = p2sh.EncodeAddress()
+
+	p2shPkScript, _ := txscript.PayToAddrScript(p2sh)
+	prevTx := wire.NewMsgTx(txVersion)
+	prevTx.AddTxIn(wire.NewTxIn(&wire.OutPoint{Hash: chainhash.Hash{1}}, nil, nil))
+	prevTx.AddTxOut(wire.NewTxOut(100000, p2shPkScript))
+	ms.p2shPrevTxHex, _ = getTxHex(prevTx)
+	ms.p2shPrevTxId = prevTx.TxHash().String()
+	return ms
+}
+
+func (ms *testMultisig) inputs() []*TxInput {
+	return []*TxInput{
+		{
+			TxId:          "25b9d08a26c8d47795301dd47a861cff0459d14f27fbd41cffaca17d9aa20f87",
+			VOut:          uint32(0),
+			Amount:        int64(100000),
+			Address:       ms.p2wsh,
+			PrivateKey:    ms.wifs[0],
+			PrivateKeys:   []string{ms.wifs[2]},
+			WitnessScript: ms.script,
+		},
+		{
+			TxId:          "25b9d08a26c8d47795301dd47a861cff0459d14f27fbd41cffaca17d9aa20f87",
+			VOut:          uint32(1),
+			Amount:        int64(100000),
+			Address:       ms.p2shP2wsh,
+			PrivateKeys:   []string{ms.wifs[2], ms.wifs[1]},
+			WitnessScript: ms.script,
+		},
+		{
+			TxId:           ms.p2shPrevTxId,
+			VOut:           uint32(0),
+			Amount:         int64(100000),
+			Address:        ms.p2sh,
+			PrivateKey:     ms.wifs[1],
+			PrivateKeys:    []string{ms.wifs[0]},
+			RedeemScript:   ms.script,
+			NonWitnessUtxo: ms.p2shPrevTxHex,
+		},
+	}
+}
+
+func verifyTestTx(t *testing.T, txHex string, ins []*TxInput) {
+	tx, err := newTxFromHex(txHex)
+	if err != nil {
+		t.Fatal(err)
+	}
+	prevOuts := txscript.NewMultiPrevOutFetcher(nil)
+	for i, in := range ins {
+		pkScript, _ := AddrToPkScript(in.Address, &chaincfg.TestNet3Params)
+		prevOuts.AddPrevOut(tx.TxIn[i].PreviousOutPoint, wire.NewTxOut(in.Amount, pkScript))
+	}
+	for i := range tx.TxIn {
+		if err := verifyInput(tx, i, prevOuts); err != nil {
+			t.Fatalf("input %d: %v", i, err)
+		}
+	}
+}
+
+func TestTransferMultisig(t *testing.T) {
+	network := &chaincfg.TestNet3Params
+	ms := newTestMultisig(t)
+	outs := []*TxOutput{{Address: "tb1qtsq9c4fje6qsmheql8gajwtrrdrs38kdzeersc", Amount: 250000}}
+
+	ins := ms.inputs()
+	result, err := TransferWithChange(ins, outs, "tb1qtsq9c4fje6qsmheql8gajwtrrdrs38kdzeersc", 3, network)
+	if err != nil {
+		t.Fatal(err)
+	}
+	verifyTestTx(t, result.Tx, ins)
+	if result.Fee < FeeRate(3).FeeForVSize(result.VSize) {
+		t.Fatalf("fee %d below 3 sat/vB for %d vB", result.Fee, result.VSize)
+	}
+
+	// one key per party, combined through PSBTs
+	partial := ms.inputs()
+	for _, in := range partial {
+		in.PrivateKey, in.PrivateKeys = ms.wifs[0], nil
+	}
+	if _, err = Transfer(partial, outs, network); !errors.Is(err, ErrMultisigThreshold) {
+		t.Fatalf("expected ErrMultisigThreshold, got %v", err)
+	}
+	packet, err := TransferPSBT(partial, outs, network)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cosigner, err := NewKeySigner(ms.wifs[2])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if packet, err = SignPSBT(packet, cosigner); err != nil {
+		t.Fatal(err)
+	}
+	if packet, err = FinalizePSBT(packet); err != nil {
+		t.Fatal(err)
+	}
+	txHex, err := ExtractTx(packet)
+	if err != nil {
+		t.Fatal(err)
+	}
+	verifyTestTx(t, txHex, partial)
+
+	wrongScript := ms.inputs()[:1]
+	wrongScript[0].WitnessScript = wrongScript[0].WitnessScript[1:]
+	if _, err = Transfer(wrongScript, outs, network); !errors.Is(err, ErrInvalidMultisig) {
+		t.Fatalf("expected ErrInvalidMultisig, got %v", err)
+	}
+}
+
+func TestMultisigPSBTCosigners(t *testing.T) {
+	network := &chaincfg.TestNet3Params
+	ms := newTestMultisig(t)
+	outs := []*TxOutput{{Address: "tb1qtsq9c4fje6qsmheql8gajwtrrdrs38kdzeersc", Amount: 250000}}
+
+	// the creator holds none of the keys
+	ins := ms.inputs()
+	for _, in := range ins {
+		in.PrivateKey, in.PrivateKeys = "", nil
+	}
+	unsigned, err := TransferPSBT(ins, outs, network)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// every cosigner signs the unsigned PSBT on its own
+	var signed []string
+	for _, wif := range ms.wifs {
+		cosigner, err := NewKeySigner(wif)
+		if err != nil {
+			t.Fatal(err)
+		}
+		packet, err := SignPSBT(unsigned, cosigner)
+		if err != nil {
+			t.Fatal(err)
+		}
+		signed = append(signed, packet)
+	}
+
+	for _, packets := range [][]string{signed[:2], signed[1:], signed} {
+		combined, err := CombinePSBTs(packets...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if combined, err = FinalizePSBT(combined); err != nil {
+			t.Fatal(err)
+		}
+		txHex, err := ExtractTx(combined)
+		if err != nil {
+			t.Fatal(err)
+		}
+		verifyTestTx(t, txHex, ins)
+	}
+
+	if _, err = FinalizePSBT(signed[0]); err == nil {
+		t.Fatal("expected an error finalizing a single signature")
+	}
+}
+
+func TestPrepareDummyUTXOsMultisig(t *testing.T) {
+	network := &chaincfg.TestNet3Params
+	ms := newTestMultisig(t)
+
+	request := &DummyUTXORequest{
+		FundingInputs: []*PrevOutput{{
+			TxId:          "25b9d08a26c8d47795301dd47a861cff0459d14f27fbd41cffaca17d9aa20f87",
+			VOut:          uint32(0),
+			Amount:        int64(100000),
+			Address:       ms.p2shP2wsh,
+			PrivateKeys:   []string{ms.wifs[1], ms.wifs[2]},
+			WitnessScript: hex.EncodeToString(ms.script),
+		}},
+		Address:       "tb1pklh8lqax5l7m2ycypptv2emc4gata2dy28svnwcp9u32wlkenvsspcvhsr",
+		Count:         2,
+		ChangeAddress: "tb1qtsq9c4fje6qsmheql8gajwtrrdrs38kdzeersc",
+		FeeRate:       2,
+	}
+	dummies, err := PrepareDummyUTXOs(request, network)
+	if err != nil {
+		t.Fatal(err)
+	}
+	verifyTestTx(t, dummies.Tx, []*TxInput{{Address: ms.p2shP2wsh, Amount: 100000}})
+
+	request.FundingInputs[0].PrivateKeys = request.FundingInputs[0].PrivateKeys[1:]
+	if _, err = PrepareDummyUTXOs(request, network); !errors.Is(err, ErrMultisigThreshold) {
+		t.Fatalf("expected ErrMultisigThreshold, got %v", err)
+	}
+}
@@ -0,0 +1,444 @@
+package brc20
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/btcec/v2/schnorr"
+	"github.com/btcsuite/btcd/btcec/v2/schnorr/musig2"
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/btcutil/psbt"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+)
+
+var (
+	ErrInvalidMuSig2  = errors.New("invalid musig2")
+	ErrMuSig2Unsigned = errors.New("input needs musig2 signing")
+)
+
+// MuSig2Nonce is the nonce of one signer for one signing session. PublicNonce is
+// shared with the other signers. SecretNonce is kept private and must never be
+// used for a second signature.
+type MuSig2Nonce struct {
+	PublicNonce string `json:"publicNonce"`
+	SecretNonce string `json:"secretNonce"`
+}
+
+// MuSig2AggregateKey returns the hex x-only MuSig2 aggregate of pubKeys, hex
+// compressed public keys in any order. It is the taproot internal key of the
+// addresses the signers control together.
+func MuSig2AggregateKey(pubKeys []string) (string, error) {
+	keys, err := parsePubKeys(pubKeys)
+	if err != nil {
+		return "", err
+	}
+	aggregateKey, _, _, err := musig2.AggregateKeys(keys, true)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(schnorr.SerializePubKey(aggregateKey.PreTweakedKey)), nil
+}
+
+// MuSig2Address returns the taproot address with the MuSig2 aggregate of pubKeys as
+// internal key, committing to tapMerkleRoot, or to no script for an empty one.
+func MuSig2Address(pubKeys []string, tapMerkleRoot []byte, network *chaincfg.Params) (string, error) {
+	internalKey, err := MuSig2AggregateKey(pubKeys)
+	if err != nil {
+		return "", err
+	}
+	key, err := parseInternalKey(internalKey)
+	if err != nil {
+		return "", err
+	}
+	address, err := btcutil.NewAddressTaproot(schnorr.SerializePubKey(txscript.ComputeTaprootOutputKey(key, tapMerkleRoot)), network)
+	if err != nil {
+		return "", err
+	}
+	return address.EncodeAddress(), nil
+}
+
+// MuSig2GenNonce is the first signing round: it generates the nonce of the signer
+// holding privateKey for one input.
+func MuSig2GenNonce(privateKey string, pubKeys []string) (*MuSig2Nonce, error) {
+	wif, err := btcutil.DecodeWIF(privateKey)
+	if err != nil {
+		return nil, err
+	}
+	keys, err := parsePubKeys(pubKeys)
+	if err != nil {
+		return nil, err
+	}
+	aggregateKey, _, _, err := musig2.AggregateKeys(keys, true)
+	if err != nil {
+		return nil, err
+	}
+	nonces, err := musig2.GenNonces(musig2.WithPublicKey(wif.PrivKey.PubKey()),
+		musig2.WithNonceSecretKeyAux(wif.PrivKey), musig2.WithNonceCombinedKeyAux(aggregateKey.PreTweakedKey))
+	if err != nil {
+		return nil, err
+	}
+	return &MuSig2Nonce{
+		PublicNonce: hex.EncodeToString(nonces.PubNonce[:]),
+		SecretNonce: hex.EncodeToString(nonces.SecNonce[:]),
+	}, nil
+}
+
+// MuSig2PartialSign is the second signing round: once every signer has shared its
+// public nonce, it returns the hex partial signature of input index of packet, a
+// PSBT from TransferPSBT. publicNonces are in any order.
+func MuSig2PartialSign(packet string, index int, privateKey string, secretNonce string, pubKeys, publicNonces []string) (string, error) {
+	session, err := newMuSig2Session(packet, index, pubKeys, publicNonces)
+	if err != nil {
+		return "", err
+	}
+	return session.partialSign(privateKey, secretNonce)
+}
+
+// MuSig2Combine is the last signing round: it verifies the partial signatures,
+// partialSigs[i] by the signer of pubKeys[i] with publicNonces[i], and adds the
+// aggregated key path signature to input index of packet. The result uses the
+// encoding of packet, ready for FinalizePSBT.
+func MuSig2Combine(packet string, index int, pubKeys, publicNonces, partialSigs []string) (string, error) {
+	session, err := newMuSig2Session(packet, index, pubKeys, publicNonces)
+	if err != nil {
+		return "", err
+	}
+	sig, err := session.combine(partialSigs)
+	if err != nil {
+		return "", err
+	}
+	session.packet.Inputs[index].TaprootKeySpendSig = appendTaprootSigHashType(sig.Serialize(), session.hashType)
+	return encodePSBT(session.packet, session.isHex)
+}
+
+// MuSig2PartialSignReveal is the second signing round of a reveal tx of Inscribe
+// with CommitMuSig2PubKeys: it returns the hex partial signature of the
+// inscription script spend of revealTx, which spends an output of commitTx.
+func MuSig2PartialSignReveal(commitTx, revealTx string, privateKey string, secretNonce string, pubKeys, publicNonces []string) (string, error) {
+	session, err := newMuSig2RevealSession(commitTx, revealTx, pubKeys, publicNonces)
+	if err != nil {
+		return "", err
+	}
+	return session.partialSign(privateKey, secretNonce)
+}
+
+// MuSig2CombineReveal is the last signing round of a reveal tx of Inscribe with
+// CommitMuSig2PubKeys: it verifies the partial signatures, in the order of pubKeys
+// like MuSig2Combine, and returns the hex of the signed reveal tx.
+func MuSig2CombineReveal(commitTx, revealTx string, pubKeys, publicNonces, partialSigs []string) (string, error) {
+	session, err := newMuSig2RevealSession(commitTx, revealTx, pubKeys, publicNonces)
+	if err != nil {
+		return "", err
+	}
+	sig, err := session.combine(partialSigs)
+	if err != nil {
+		return "", err
+	}
+	txIn := session.revealTx.TxIn[0]
+	txIn.Witness = append(wire.TxWitness{sig.Serialize()}, txIn.Witness...)
+	if err = verifyInput(session.revealTx, 0, session.prevOuts); err != nil {
+		return "", fmt.Errorf("%w: %v", ErrInvalidMuSig2, err)
+	}
+	return getTxHex(session.revealTx)
+}
+
+// musig2Session is the state the second and third rounds derive from the PSBT, or
+// from the reveal tx for an inscription script spend.
+type musig2Session struct {
+	packet         *psbt.Packet
+	isHex          bool
+	revealTx       *wire.MsgTx
+	prevOuts       txscript.PrevOutputFetcher
+	keys           []*btcec.PublicKey
+	publicNonces   [][musig2.PubNonceSize]byte
+	aggregateNonce [musig2.PubNonceSize]byte
+	tapMerkleRoot  []byte
+	// scriptPath is set for a script spend, signed by the untweaked aggregate key
+	scriptPath bool
+	// signingKey is the key the aggregated signature verifies against
+	signingKey *btcec.PublicKey
+	hashType   txscript.SigHashType
+	sigHash    [32]byte
+}
+
+func newMuSig2Session(packet string, index int, pubKeys, publicNonces []string) (*musig2Session, error) {
+	bp, isHex, err := decodePSBT(packet)
+	if err != nil {
+		return nil, err
+	}
+	if index < 0 || index >= len(bp.Inputs) {
+		return nil, fmt.Errorf("%w: no input %d", ErrInvalidMuSig2, index)
+	}
+	session := &musig2Session{packet: bp, isHex: isHex}
+	if err = session.aggregateNonces(pubKeys, publicNonces); err != nil {
+		return nil, err
+	}
+
+	prevOuts, complete, err := psbtPrevOuts(bp)
+	if err != nil {
+		return nil, err
+	}
+	if !complete {
+		return nil, fmt.Errorf("%w: the taproot sighash needs every input utxo", ErrMissingUtxo)
+	}
+	session.prevOuts = prevOuts
+	prevOut := prevOuts.FetchPrevOutput(bp.UnsignedTx.TxIn[index].PreviousOutPoint)
+	pInput := bp.Inputs[index]
+	session.tapMerkleRoot = pInput.TaprootMerkleRoot
+	aggregateKey, _, _, err := musig2.AggregateKeys(session.keys, true)
+	if err != nil {
+		return nil, err
+	}
+	session.signingKey = txscript.ComputeTaprootOutputKey(aggregateKey.PreTweakedKey, session.tapMerkleRoot)
+	if !txscript.IsPayToTaproot(prevOut.PkScript) || !bytes.Equal(prevOut.PkScript[2:], schnorr.SerializePubKey(session.signingKey)) {
+		return nil, fmt.Errorf("%w: input %d does not pay to the aggregate key", ErrInvalidMuSig2, index)
+	}
+
+	session.hashType = pInput.SighashType
+	if session.hashType == txscript.SigHashAll {
+		session.hashType = txscript.SigHashDefault
+	}
+	sigHash, err := txscript.CalcTaprootSignatureHash(txscript.NewTxSigHashes(bp.UnsignedTx, prevOuts),
+		session.hashType, bp.UnsignedTx, index, prevOuts)
+	if err != nil {
+		return nil, err
+	}
+	copy(session.sigHash[:], sigHash)
+	return session, nil
+}
+
+// newMuSig2RevealSession checks that revealTx is an unsigned reveal of commitTx,
+// whose inscription script is signed by the aggregate of pubKeys.
+func newMuSig2RevealSession(commitTx, revealTx string, pubKeys, publicNonces []string) (*musig2Session, error) {
+	commit, err := newTxFromHex(commitTx)
+	if err != nil {
+		return nil, err
+	}
+	reveal, err := newTxFromHex(revealTx)
+	if err != nil {
+		return nil, err
+	}
+	if len(reveal.TxIn) != 1 || reveal.TxIn[0].PreviousOutPoint.Hash != commit.TxHash() ||
+		int(reveal.TxIn[0].PreviousOutPoint.Index) >= len(commit.TxOut) {
+		return nil, fmt.Errorf("%w: the reveal tx does not spend the commit tx", ErrInvalidMuSig2)
+	}
+	// an unsigned reveal has the inscription script and the control block
+	witness := reveal.TxIn[0].Witness
+	if len(witness) != 2 {
+		return nil, fmt.Errorf("%w: the reveal tx is not an unsigned inscription script spend", ErrInvalidMuSig2)
+	}
+	session := &musig2Session{revealTx: reveal, scriptPath: true, hashType: txscript.SigHashDefault}
+	if err = session.aggregateNonces(pubKeys, publicNonces); err != nil {
+		return nil, err
+	}
+	aggregateKey, _, _, err := musig2.AggregateKeys(session.keys, true)
+	if err != nil {
+		return nil, err
+	}
+	session.signingKey = aggregateKey.PreTweakedKey
+	inscriptionScript := witness[0]
+	if !bytes.HasPrefix(inscriptionScript, append([]byte{txscript.OP_DATA_32}, schnorr.SerializePubKey(session.signingKey)...)) {
+		return nil, fmt.Errorf("%w: the inscription script is not signed by the aggregate key", ErrInvalidMuSig2)
+	}
+
+	prevOut := commit.TxOut[reveal.TxIn[0].PreviousOutPoint.Index]
+	session.prevOuts = txscript.NewCannedPrevOutputFetcher(prevOut.PkScript, prevOut.Value)
+	sigHash, err := txscript.CalcTapscriptSignaturehash(txscript.NewTxSigHashes(reveal, session.prevOuts),
+		session.hashType, reveal, 0, session.prevOuts, txscript.NewBaseTapLeaf(inscriptionScript))
+	if err != nil {
+		return nil, err
+	}
+	copy(session.sigHash[:], sigHash)
+	return session, nil
+}
+
+func (session *musig2Session) aggregateNonces(pubKeys, publicNonces []string) error {
+	var err error
+	if session.keys, err = parsePubKeys(pubKeys); err != nil {
+		return err
+	}
+	for _, publicNonce := range publicNonces {
+		var nonce [musig2.PubNonceSize]byte
+		if err = decodeFixedHex(publicNonce, nonce[:]); err != nil {
+			return err
+		}
+		session.publicNonces = append(session.publicNonces, nonce)
+	}
+	if len(session.publicNonces) != len(session.keys) {
+		return fmt.Errorf("%w: need a public nonce per key", ErrInvalidMuSig2)
+	}
+	if session.aggregateNonce, err = musig2.AggregateNonces(session.publicNonces); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidMuSig2, err)
+	}
+	return nil
+}
+
+func (session *musig2Session) partialSign(privateKey, secretNonce string) (string, error) {
+	wif, err := btcutil.DecodeWIF(privateKey)
+	if err != nil {
+		return "", err
+	}
+	var secNonce [musig2.SecNonceSize]byte
+	if err = decodeFixedHex(secretNonce, secNonce[:]); err != nil {
+		return "", err
+	}
+	partialSig, err := musig2.Sign(secNonce, wif.PrivKey, session.aggregateNonce, session.keys, session.sigHash,
+		append(session.signOptions(), musig2.WithSortedKeys())...)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrInvalidMuSig2, err)
+	}
+	var buf bytes.Buffer
+	if err = partialSig.Encode(&buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf.Bytes()), nil
+}
+
+// combine verifies partialSigs, one per key in the order of the keys, and returns
+// the aggregated signature.
+func (session *musig2Session) combine(partialSigs []string) (*schnorr.Signature, error) {
+	if len(partialSigs) != len(session.keys) {
+		return nil, fmt.Errorf("%w: need a public nonce and a partial signature per key", ErrInvalidMuSig2)
+	}
+	var sigs []*musig2.PartialSignature
+	for i, partialSig := range partialSigs {
+		var s [32]byte
+		if err := decodeFixedHex(partialSig, s[:]); err != nil {
+			return nil, err
+		}
+		sig := &musig2.PartialSignature{S: new(btcec.ModNScalar)}
+		if overflow := sig.S.SetBytes(&s); overflow != 0 {
+			return nil, fmt.Errorf("%w: partial signature %d", ErrInvalidMuSig2, i)
+		}
+		if !sig.Verify(session.publicNonces[i], session.aggregateNonce, session.keys, session.keys[i], session.sigHash,
+			append(session.signOptions(), musig2.WithSortedKeys())...) {
+			return nil, fmt.Errorf("%w: partial signature %d does not verify", ErrInvalidMuSig2, i)
+		}
+		sigs = append(sigs, sig)
+	}
+
+	nonce, err := musig2SigningNonce(session.aggregateNonce, session.signingKey, session.sigHash)
+	if err != nil {
+		return nil, err
+	}
+	var combineOptions []musig2.CombineOption
+	switch {
+	case session.scriptPath:
+	case len(session.tapMerkleRoot) > 0:
+		combineOptions = append(combineOptions, musig2.WithTaprootTweakedCombine(session.sigHash, session.keys, session.tapMerkleRoot, true))
+	default:
+		combineOptions = append(combineOptions, musig2.WithBip86TweakedCombine(session.sigHash, session.keys, true))
+	}
+	sig := musig2.CombineSigs(nonce, sigs, combineOptions...)
+	if !sig.Verify(session.sigHash[:], session.signingKey) {
+		return nil, fmt.Errorf("%w: aggregated signature does not verify", ErrInvalidMuSig2)
+	}
+	return sig, nil
+}
+
+func (session *musig2Session) signOptions() []musig2.SignOption {
+	if session.scriptPath {
+		return nil
+	}
+	if len(session.tapMerkleRoot) > 0 {
+		return []musig2.SignOption{musig2.WithTaprootSignTweak(session.tapMerkleRoot)}
+	}
+	return []musig2.SignOption{musig2.WithBip86SignTweak()}
+}
+
+// musig2SigningNonce returns the final nonce R = R1 + b*R2 of a signing session,
+// which the partial signatures do not carry once encoded.
+func musig2SigningNonce(aggregateNonce [musig2.PubNonceSize]byte, signingKey *btcec.PublicKey, sigHash [32]byte) (*btcec.PublicKey, error) {
+	var msg bytes.Buffer
+	msg.Write(aggregateNonce[:])
+	msg.Write(schnorr.SerializePubKey(signingKey))
+	msg.Write(sigHash[:])
+	var b btcec.ModNScalar
+	b.SetByteSlice(chainhash.TaggedHash(musig2.NonceBlindTag, msg.Bytes())[:])
+
+	r1, err := btcec.ParseJacobian(aggregateNonce[:btcec.PubKeyBytesLenCompressed])
+	if err != nil {
+		return nil, err
+	}
+	r2, err := btcec.ParseJacobian(aggregateNonce[btcec.PubKeyBytesLenCompressed:])
+	if err != nil {
+		return nil, err
+	}
+	var nonce btcec.JacobianPoint
+	btcec.ScalarMultNonConst(&b, &r2, &r2)
+	btcec.AddNonConst(&r1, &r2, &nonce)
+	if (nonce.X.IsZero() && nonce.Y.IsZero()) || nonce.Z.IsZero() {
+		btcec.Generator().AsJacobian(&nonce)
+	}
+	nonce.ToAffine()
+	return btcec.NewPublicKey(&nonce.X, &nonce.Y), nil
+}
+
+// musig2InternalKey returns the x-only aggregate internal key of a musig2 input, nil otherwise.
+func (in *TxInput) musig2InternalKey(pkScript []byte) ([]byte, error) {
+	if len(in.MuSig2PubKeys) == 0 {
+		return nil, nil
+	}
+	internalKey, err := MuSig2AggregateKey(in.MuSig2PubKeys)
+	if err != nil {
+		return nil, err
+	}
+	key, err := parseInternalKey(internalKey)
+	if err != nil {
+		return nil, err
+	}
+	outputKey := txscript.ComputeTaprootOutputKey(key, in.TapMerkleRoot)
+	if !txscript.IsPayToTaproot(pkScript) || !bytes.Equal(pkScript[2:], schnorr.SerializePubKey(outputKey)) {
+		return nil, fmt.Errorf("%w: %s does not pay to the aggregate key", ErrInvalidMuSig2, in.Address)
+	}
+	return schnorr.SerializePubKey(key), nil
+}
+
+func parsePubKeys(pubKeys []string) ([]*btcec.PublicKey, error) {
+	if len(pubKeys) < 2 {
+		return nil, fmt.Errorf("%w: need at least 2 keys", ErrInvalidMuSig2)
+	}
+	var keys []*btcec.PublicKey
+	for _, pubKey := range pubKeys {
+		keyBytes, err := hex.DecodeString(pubKey)
+		if err != nil {
+			return nil, err
+		}
+		key, err := btcec.ParsePubKey(keyBytes)
+		if err != nil {
+			return nil, fmt.Errorf("%w: public key %s: %v", ErrInvalidMuSig2, pubKey, err)
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// parseInternalKey parses a hex x-only or compressed taproot internal key.
+func parseInternalKey(internalKey string) (*btcec.PublicKey, error) {
+	keyBytes, err := hex.DecodeString(internalKey)
+	if err != nil {
+		return nil, err
+	}
+	if len(keyBytes) == schnorr.PubKeyBytesLen {
+		return schnorr.ParsePubKey(keyBytes)
+	}
+	return btcec.ParsePubKey(keyBytes)
+}
+
+func decodeFixedHex(s string, dst []byte) error {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		return err
+	}
+	if len(b) != len(dst) {
+		return fmt.Errorf("%w: expected %d bytes, got %d", ErrInvalidMuSig2, len(dst), len(b))
+	}
+	copy(dst, b)
+	return nil
+}
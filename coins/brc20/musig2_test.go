@@ -0,0 +1,250 @@
+package brc20
+
+import (
+	"encoding/hex"
+	"errors"
+	"testing"
+
+	"github.com/btcsuite/btcd/blockchain"
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/btcec/v2/schnorr"
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+)
+
+// newTestMuSig2Keys returns the test key and a fixed service key, with their hex
+// compressed public keys.
+func newTestMuSig2Keys(t *testing.T) ([]string, []string) {
+	wifs := []string{"cPnvkvUYyHcSSS26iD1dkrJdV7k1RoUqJLhn3CYxpo398PdLVE22"}
+	privateKey, _ := btcec.PrivKeyFromBytes(chainhash.HashB([]byte("service")))
+	wif, err := btcutil.NewWIF(privateKey, &chaincfg.TestNet3Params, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wifs = append(wifs, wif.String())
+	var pubKeys []string
+	for _, wifStr := range wifs {
+		wif, _ := btcutil.DecodeWIF(wifStr)
+		pubKeys = append(pubKeys, hex.EncodeToString(wif.PrivKey.PubKey().SerializeCompressed()))
+	}
+	return wifs, pubKeys
+}
+
+// muSig2Sign runs the nonce, partial signing and combine rounds for input index.
+func muSig2Sign(t *testing.T, packet string, index int, wifs, pubKeys []string) string {
+	var nonces []*MuSig2Nonce
+	var publicNonces []string
+	for _, wif := range wifs {
+		nonce, err := MuSig2GenNonce(wif, pubKeys)
+		if err != nil {
+			t.Fatal(err)
+		}
+		nonces = append(nonces, nonce)
+		publicNonces = append(publicNonces, nonce.PublicNonce)
+	}
+	var partialSigs []string
+	for i, wif := range wifs {
+		partialSig, err := MuSig2PartialSign(packet, index, wif, nonces[i].SecretNonce, pubKeys, publicNonces)
+		if err != nil {
+			t.Fatal(err)
+		}
+		partialSigs = append(partialSigs, partialSig)
+	}
+
+	if _, err := MuSig2Combine(packet, index, pubKeys, publicNonces, []string{partialSigs[1], partialSigs[0]}); !errors.Is(err, ErrInvalidMuSig2) {
+		t.Fatalf("expected ErrInvalidMuSig2 for swapped partial signatures, got %v", err)
+	}
+	packet, err := MuSig2Combine(packet, index, pubKeys, publicNonces, partialSigs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return packet
+}
+
+func TestMuSig2Transfer(t *testing.T) {
+	network := &chaincfg.TestNet3Params
+	wifs, pubKeys := newTestMuSig2Keys(t)
+
+	reversed, err := MuSig2AggregateKey([]string{pubKeys[1], pubKeys[0]})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if aggregateKey, _ := MuSig2AggregateKey(pubKeys); aggregateKey != reversed {
+		t.Fatalf("aggregate key depends on key order: %s, %s", aggregateKey, reversed)
+	}
+
+	tapMerkleRoot := chainhash.HashB([]byte("script tree"))
+	address, err := MuSig2Address(pubKeys, nil, network)
+	if err != nil {
+		t.Fatal(err)
+	}
+	scriptAddress, err := MuSig2Address(pubKeys, tapMerkleRoot, network)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ins := []*TxInput{
+		{
+			TxId:          "25b9d08a26c8d47795301dd47a861cff0459d14f27fbd41cffaca17d9aa20f87",
+			VOut:          uint32(0),
+			Amount:        int64(100000),
+			Address:       address,
+			MuSig2PubKeys: pubKeys,
+		},
+		{
+			TxId:          "25b9d08a26c8d47795301dd47a861cff0459d14f27fbd41cffaca17d9aa20f87",
+			VOut:          uint32(1),
+			Amount:        int64(100000),
+			Address:       scriptAddress,
+			MuSig2PubKeys: pubKeys,
+			TapMerkleRoot: tapMerkleRoot,
+		},
+		{
+			TxId:       "25b9d08a26c8d47795301dd47a861cff0459d14f27fbd41cffaca17d9aa20f87",
+			VOut:       uint32(2),
+			Amount:     int64(100000),
+			Address:    "tb1qtsq9c4fje6qsmheql8gajwtrrdrs38kdzeersc",
+			PrivateKey: wifs[0],
+		},
+	}
+	outs := []*TxOutput{{Address: "tb1pklh8lqax5l7m2ycypptv2emc4gata2dy28svnwcp9u32wlkenvsspcvhsr", Amount: 299000}}
+
+	if _, err = Transfer(ins, outs, network); !errors.Is(err, ErrMuSig2Unsigned) {
+		t.Fatalf("expected ErrMuSig2Unsigned, got %v", err)
+	}
+	packet, err := TransferPSBT(ins, outs, network)
+	if err != nil {
+		t.Fatal(err)
+	}
+	packet = muSig2Sign(t, packet, 0, wifs, pubKeys)
+	packet = muSig2Sign(t, packet, 1, wifs, pubKeys)
+	if packet, err = FinalizePSBT(packet); err != nil {
+		t.Fatal(err)
+	}
+	txHex, err := ExtractTx(packet)
+	if err != nil {
+		t.Fatal(err)
+	}
+	verifyTestTx(t, txHex, ins)
+
+	ins[0].MuSig2PubKeys = []string{pubKeys[0], hex.EncodeToString(make([]byte, 33))}
+	if _, err = TransferPSBT(ins, outs, network); !errors.Is(err, ErrInvalidMuSig2) {
+		t.Fatalf("expected ErrInvalidMuSig2, got %v", err)
+	}
+}
+
+func TestInscribeMuSig2CommitKey(t *testing.T) {
+	network := &chaincfg.TestNet3Params
+	wifs, pubKeys := newTestMuSig2Keys(t)
+	request := newTestInscriptionRequest()
+	request.CommitMuSig2PubKeys = pubKeys
+	txs, err := Inscribe(network, request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(txs.CommitTapMerkleRoots) != len(request.InscriptionDataList) {
+		t.Fatalf("expected %d commit tap merkle roots, got %d", len(request.InscriptionDataList), len(txs.CommitTapMerkleRoots))
+	}
+	commitTx, err := newTxFromHex(txs.CommitTx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	revealIns := []*TxInput{{Amount: commitTx.TxOut[0].Value}}
+	revealIns[0].Address, err = MuSig2Address(pubKeys, mustDecodeHex(t, txs.CommitTapMerkleRoots[0]), network)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the first commit input key alone cannot spend the inscription script
+	reveal, err := newTxFromHex(txs.RevealTxs[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	prevOuts := txscript.NewCannedPrevOutputFetcher(commitTx.TxOut[0].PkScript, commitTx.TxOut[0].Value)
+	sigHash, err := txscript.CalcTapscriptSignaturehash(txscript.NewTxSigHashes(reveal, prevOuts), txscript.SigHashDefault,
+		reveal, 0, prevOuts, txscript.NewBaseTapLeaf(reveal.TxIn[0].Witness[0]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	wif, _ := btcutil.DecodeWIF(request.CommitTxPrevOutputList[0].PrivateKey)
+	sig, err := schnorr.Sign(wif.PrivKey, sigHash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reveal.TxIn[0].Witness = append(wire.TxWitness{sig.Serialize()}, reveal.TxIn[0].Witness...)
+	if err = verifyInput(reveal, 0, prevOuts); err == nil {
+		t.Fatal("the first commit input key spends the inscription script alone")
+	}
+
+	// both keys sign the reveal in the musig2 rounds
+	var nonces []*MuSig2Nonce
+	var publicNonces []string
+	for _, wif := range wifs {
+		nonce, err := MuSig2GenNonce(wif, pubKeys)
+		if err != nil {
+			t.Fatal(err)
+		}
+		nonces = append(nonces, nonce)
+		publicNonces = append(publicNonces, nonce.PublicNonce)
+	}
+	var partialSigs []string
+	for i, wif := range wifs {
+		partialSig, err := MuSig2PartialSignReveal(txs.CommitTx, txs.RevealTxs[0], wif, nonces[i].SecretNonce, pubKeys, publicNonces)
+		if err != nil {
+			t.Fatal(err)
+		}
+		partialSigs = append(partialSigs, partialSig)
+	}
+	if _, err = MuSig2CombineReveal(txs.CommitTx, txs.RevealTxs[0], pubKeys, publicNonces, []string{partialSigs[1], partialSigs[0]}); !errors.Is(err, ErrInvalidMuSig2) {
+		t.Fatalf("expected ErrInvalidMuSig2 for swapped partial signatures, got %v", err)
+	}
+	revealTx, err := MuSig2CombineReveal(txs.CommitTx, txs.RevealTxs[0], pubKeys, publicNonces, partialSigs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	verifyTestTx(t, revealTx, revealIns)
+	signed, err := newTxFromHex(revealTx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fee := request.RevealFeeRate.FeeForWeight(blockchain.GetTransactionWeight(btcutil.NewTx(signed))); fee != txs.RevealTxFees[0] {
+		t.Fatalf("reveal fee %d, expected %d for the signed weight", txs.RevealTxFees[0], fee)
+	}
+	if _, err = MuSig2PartialSignReveal(txs.CommitTx, revealTx, wifs[0], nonces[0].SecretNonce, pubKeys, publicNonces); !errors.Is(err, ErrInvalidMuSig2) {
+		t.Fatalf("expected ErrInvalidMuSig2 for a signed reveal, got %v", err)
+	}
+
+	// both keys together recover the commit output through the key path
+	ins := []*TxInput{{
+		TxId:          commitTx.TxHash().String(),
+		VOut:          0,
+		Amount:        commitTx.TxOut[0].Value,
+		Address:       revealIns[0].Address,
+		MuSig2PubKeys: pubKeys,
+		TapMerkleRoot: mustDecodeHex(t, txs.CommitTapMerkleRoots[0]),
+	}}
+	outs := []*TxOutput{{Address: "tb1qtsq9c4fje6qsmheql8gajwtrrdrs38kdzeersc", Amount: commitTx.TxOut[0].Value - 200}}
+	packet, err := TransferPSBT(ins, outs, network)
+	if err != nil {
+		t.Fatal(err)
+	}
+	packet = muSig2Sign(t, packet, 0, wifs, pubKeys)
+	if packet, err = FinalizePSBT(packet); err != nil {
+		t.Fatal(err)
+	}
+	txHex, err := ExtractTx(packet)
+	if err != nil {
+		t.Fatal(err)
+	}
+	verifyTestTx(t, txHex, ins)
+}
+
+func mustDecodeHex(t *testing.T, s string) []byte {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
@@ -0,0 +1,186 @@
+package brc20
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/wire"
+)
+
+var (
+	ErrUnknownNetwork   = errors.New("unknown network")
+	ErrDuplicateNetwork = errors.New("duplicate network")
+	ErrNetworkMismatch  = errors.New("address network mismatch")
+)
+
+// TestNet4Params defines the network parameters for the BIP-94 test network,
+// which btcd does not ship yet.
+var TestNet4Params = newTestNet4Params()
+
+var networkRegistry = struct {
+	sync.RWMutex
+	byName map[string]*chaincfg.Params
+	list   []*chaincfg.Params
+}{
+	byName: make(map[string]*chaincfg.Params),
+}
+
+func init() {
+	for _, params := range []*chaincfg.Params{
+		&chaincfg.MainNetParams,
+		&chaincfg.TestNet3Params,
+		TestNet4Params,
+		&chaincfg.SigNetParams,
+		&chaincfg.RegressionNetParams,
+		LitecoinMainNetParams,
+		LitecoinTestNetParams,
+		DogecoinMainNetParams,
+		DogecoinTestNetParams,
+	} {
+		if err := RegisterNetwork(params.Name, params); err != nil {
+			panic("failed to register network: " + err.Error())
+		}
+	}
+}
+
+// RegisterNetwork makes params available by name and by bech32 HRP. The params are
+// also registered with chaincfg, so their address prefixes can be decoded.
+func RegisterNetwork(name string, params *chaincfg.Params) error {
+	networkRegistry.Lock()
+	defer networkRegistry.Unlock()
+
+	if _, ok := networkRegistry.byName[name]; ok {
+		return fmt.Errorf("%w: %s", ErrDuplicateNetwork, name)
+	}
+	if err := chaincfg.Register(params); err != nil && !errors.Is(err, chaincfg.ErrDuplicateNet) {
+		return err
+	}
+	networkRegistry.byName[name] = params
+	networkRegistry.list = append(networkRegistry.list, params)
+	return nil
+}
+
+// unregisterNetwork removes the network registered as name. chaincfg has no way to
+// forget its address prefixes, which RegisterNetwork tolerates.
+func unregisterNetwork(name string) {
+	networkRegistry.Lock()
+	defer networkRegistry.Unlock()
+
+	params, ok := networkRegistry.byName[name]
+	if !ok {
+		return
+	}
+	delete(networkRegistry.byName, name)
+	for i, p := range networkRegistry.list {
+		if p == params {
+			networkRegistry.list = append(networkRegistry.list[:i], networkRegistry.list[i+1:]...)
+			break
+		}
+	}
+}
+
+func NetworkByName(name string) (*chaincfg.Params, error) {
+	networkRegistry.RLock()
+	defer networkRegistry.RUnlock()
+
+	params, ok := networkRegistry.byName[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownNetwork, name)
+	}
+	return params, nil
+}
+
+// NetworkByHRP returns the first registered network using the bech32 HRP. testnet3,
+// testnet4 and signet all use "tb", for which testnet3 is returned.
+func NetworkByHRP(hrp string) (*chaincfg.Params, error) {
+	networkRegistry.RLock()
+	defer networkRegistry.RUnlock()
+
+	for _, params := range networkRegistry.list {
+		if hrp != "" && params.Bech32HRPSegwit == hrp {
+			return params, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: hrp %s", ErrUnknownNetwork, hrp)
+}
+
+// NewSignetParams returns the parameters of a custom signet with the given block
+// challenge script. Register it with RegisterNetwork to look it up by name.
+func NewSignetParams(challenge []byte, dnsSeeds []chaincfg.DNSSeed) *chaincfg.Params {
+	params := chaincfg.CustomSignetParams(challenge, dnsSeeds)
+	return &params
+}
+
+// DecodeAddress decodes addr and checks that it belongs to network.
+func DecodeAddress(addr string, network *chaincfg.Params) (btcutil.Address, error) {
+	address, err := btcutil.DecodeAddress(addr, network)
+	if err == nil && address.IsForNet(network) {
+		return address, nil
+	}
+	// base58 addresses of another network fail to decode, bech32 ones decode fine
+	if name := addressNetworkName(addr); name != "" {
+		return nil, fmt.Errorf("%w: %s is a %s address, expected %s", ErrNetworkMismatch, addr, name, network.Name)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return nil, fmt.Errorf("%w: %s is not a %s address", ErrNetworkMismatch, addr, network.Name)
+}
+
+func addressNetworkName(addr string) string {
+	networkRegistry.RLock()
+	defer networkRegistry.RUnlock()
+
+	for _, params := range networkRegistry.list {
+		if address, err := btcutil.DecodeAddress(addr, params); err == nil && address.IsForNet(params) {
+			return params.Name
+		}
+	}
+	return ""
+}
+
+func newTestNet4Params() *chaincfg.Params {
+	coinbaseScript := []byte{0x04, 0xff, 0xff, 0x00, 0x1d, 0x01, 0x04, 0x4c, 0x4c}
+	coinbaseScript = append(coinbaseScript, []byte("03/May/2024 000000000000000000001ebd58c244970b3aa9d783bb001011fbe8ea8e98e00e")...)
+	outputScript := append(append([]byte{0x21}, make([]byte, 33)...), 0xac)
+
+	coinbaseTx := wire.NewMsgTx(1)
+	coinbaseTx.AddTxIn(&wire.TxIn{
+		PreviousOutPoint: wire.OutPoint{Index: wire.MaxPrevOutIndex},
+		SignatureScript:  coinbaseScript,
+		Sequence:         wire.MaxTxInSequenceNum,
+	})
+	coinbaseTx.AddTxOut(wire.NewTxOut(50*btcutil.SatoshiPerBitcoin, outputScript))
+
+	genesisBlock := &wire.MsgBlock{
+		Header: wire.BlockHeader{
+			Version:    1,
+			MerkleRoot: coinbaseTx.TxHash(),
+			Timestamp:  time.Unix(1714777860, 0),
+			Bits:       0x1d00ffff,
+			Nonce:      393743547,
+		},
+		Transactions: []*wire.MsgTx{coinbaseTx},
+	}
+	genesisHash := genesisBlock.BlockHash()
+
+	params := chaincfg.TestNet3Params
+	params.Name = "testnet4"
+	params.Net = wire.BitcoinNet(0x283f161c)
+	params.DefaultPort = "48333"
+	params.DNSSeeds = []chaincfg.DNSSeed{
+		{Host: "seed.testnet4.bitcoin.sprovoost.nl", HasFiltering: true},
+		{Host: "seed.testnet4.wiz.biz", HasFiltering: true},
+	}
+	params.GenesisBlock = genesisBlock
+	params.GenesisHash = &genesisHash
+	params.BIP0034Height = 1
+	params.BIP0065Height = 1
+	params.BIP0066Height = 1
+	params.Checkpoints = nil
+	return &params
+}
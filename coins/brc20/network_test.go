@@ -0,0 +1,80 @@
+package brc20
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/btcsuite/btcd/chaincfg"
+)
+
+func TestNetworkRegistry(t *testing.T) {
+	for _, name := range []string{"mainnet", "testnet3", "testnet4", "signet", "regtest"} {
+		params, err := NetworkByName(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if params.Name != name {
+			t.Fatalf("NetworkByName(%s) returned %s", name, params.Name)
+		}
+	}
+	if _, err := NetworkByName("bogus"); !errors.Is(err, ErrUnknownNetwork) {
+		t.Fatalf("expected ErrUnknownNetwork, got %v", err)
+	}
+
+	hrps := map[string]string{"bc": "mainnet", "tb": "testnet3", "bcrt": "regtest"}
+	for hrp, name := range hrps {
+		params, err := NetworkByHRP(hrp)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if params.Name != name {
+			t.Fatalf("NetworkByHRP(%s) returned %s, want %s", hrp, params.Name, name)
+		}
+	}
+
+	if TestNet4Params.GenesisHash.String() != "00000000da84f2bafbbc53dee25a72ae507ff4914b867c565be350b0da8bf043" {
+		t.Fatalf("wrong testnet4 genesis hash %s", TestNet4Params.GenesisHash)
+	}
+
+	// OP_TRUE challenge
+	customSignet := NewSignetParams([]byte{0x51}, nil)
+	if err := RegisterNetwork("mysignet", customSignet); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { unregisterNetwork("mysignet") })
+	if err := RegisterNetwork("mysignet", customSignet); !errors.Is(err, ErrDuplicateNetwork) {
+		t.Fatalf("expected ErrDuplicateNetwork, got %v", err)
+	}
+	if params, _ := NetworkByName("mysignet"); params.Net == chaincfg.SigNetParams.Net {
+		t.Fatal("custom signet shares the default signet network magic")
+	}
+}
+
+func TestDecodeAddressNetworkMismatch(t *testing.T) {
+	tests := []struct {
+		address string
+		network *chaincfg.Params
+		ok      bool
+	}{
+		{"tb1qtsq9c4fje6qsmheql8gajwtrrdrs38kdzeersc", &chaincfg.TestNet3Params, true},
+		{"tb1qtsq9c4fje6qsmheql8gajwtrrdrs38kdzeersc", TestNet4Params, true},
+		{"tb1qtsq9c4fje6qsmheql8gajwtrrdrs38kdzeersc", &chaincfg.MainNetParams, false},
+		{"tb1pklh8lqax5l7m2ycypptv2emc4gata2dy28svnwcp9u32wlkenvsspcvhsr", &chaincfg.RegressionNetParams, false},
+		{"2NF33rckfiQTiE5Guk5ufUdwms8PgmtnEdc", &chaincfg.SigNetParams, true},
+		{"2NF33rckfiQTiE5Guk5ufUdwms8PgmtnEdc", &chaincfg.MainNetParams, false},
+		{"mouQtmBWDS7JnT65Grj2tPzdSmGKJgRMhE", &chaincfg.MainNetParams, false},
+	}
+	for _, test := range tests {
+		_, err := DecodeAddress(test.address, test.network)
+		if test.ok && err != nil {
+			t.Errorf("%s on %s: %v", test.address, test.network.Name, err)
+		}
+		if !test.ok && !errors.Is(err, ErrNetworkMismatch) {
+			t.Errorf("%s on %s: expected ErrNetworkMismatch, got %v", test.address, test.network.Name, err)
+		}
+	}
+
+	if _, err := AddrToPkScript("tb1qtsq9c4fje6qsmheql8gajwtrrdrs38kdzeersc", &chaincfg.MainNetParams); !errors.Is(err, ErrNetworkMismatch) {
+		t.Errorf("expected ErrNetworkMismatch, got %v", err)
+	}
+}
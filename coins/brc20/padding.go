@@ -0,0 +1,158 @@
+package brc20
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/btcsuite/btcd/blockchain"
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/mempool"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+)
+
+const (
+	DefaultPaddingValue = int64(600)
+)
+
+var (
+	ErrPaddingUtxo = errors.New("padding utxo must not be spent")
+)
+
+type DummyUTXORequest struct {
+	FundingInputs []*PrevOutput `json:"fundingInputs"`
+	Address       string        `json:"address"`
+	Count         int           `json:"count"`
+	// Value of every padding output, default 600
+	Value         int64   `json:"value"`
+	ChangeAddress string  `json:"changeAddress"`
+	FeeRate       FeeRate `json:"feeRate"`
+	// FeeGuard bounds the fee, default DefaultFeeGuard
+	FeeGuard *FeeGuard `json:"feeGuard"`
+}
+
+type DummyUTXOs struct {
+	Tx     string `json:"tx"`
+	Fee    int64  `json:"fee"`
+	Change int64  `json:"change"`
+	// Utxos are the padding outputs of Tx, with IsPadding set
+	Utxos []*PrevOutput `json:"utxos"`
+}
+
+// PrepareDummyUTXOs splits the funding inputs into Count padding outputs to Address
+// plus change, for use as FillListing padding inputs. The returned utxos are marked
+// as padding, so Transfer and Inscribe refuse to spend them.
+func PrepareDummyUTXOs(request *DummyUTXORequest, network *chaincfg.Params) (*DummyUTXOs, error) {
+	if err := checkNotPadding(request.FundingInputs); err != nil {
+		return nil, err
+	}
+	if request.Count <= 0 {
+		return nil, fmt.Errorf("invalid padding output count %d", request.Count)
+	}
+	if err := request.FeeGuard.orDefault().checkFeeRate(request.FeeRate); err != nil {
+		return nil, err
+	}
+	value := DefaultPaddingValue
+	if request.Value > 0 {
+		value = request.Value
+	}
+	paddingPkScript, err := AddrToPkScript(request.Address, network)
+	if err != nil {
+		return nil, err
+	}
+	changePkScript, err := AddrToPkScript(request.ChangeAddress, network)
+	if err != nil {
+		return nil, err
+	}
+
+	var privateKeys []*btcec.PrivateKey
+	var multisigs []*multisigInput
+	prevOutFetcher := txscript.NewMultiPrevOutFetcher(nil)
+	totalIn := int64(0)
+	tx := wire.NewMsgTx(DefaultTxVersion)
+	for _, prevOutput := range request.FundingInputs {
+		privateKey, multisig, err := prevOutput.keys(network)
+		if err != nil {
+			return nil, err
+		}
+		privateKeys = append(privateKeys, privateKey)
+		multisigs = append(multisigs, multisig)
+		txHash, err := chainhash.NewHashFromStr(prevOutput.TxId)
+		if err != nil {
+			return nil, err
+		}
+		outPoint := wire.NewOutPoint(txHash, prevOutput.VOut)
+		pkScript, err := AddrToPkScript(prevOutput.Address, network)
+		if err != nil {
+			return nil, err
+		}
+		prevOutFetcher.AddPrevOut(*outPoint, wire.NewTxOut(prevOutput.Amount, pkScript))
+
+		in := wire.NewTxIn(outPoint, nil, nil)
+		in.Sequence = DefaultSequenceNum
+		tx.AddTxIn(in)
+		totalIn += prevOutput.Amount
+	}
+	for i := 0; i < request.Count; i++ {
+		tx.AddTxOut(wire.NewTxOut(value, paddingPkScript))
+	}
+	if mempool.IsDust(tx.TxOut[0], mempool.DefaultMinRelayTxFee) {
+		return nil, fmt.Errorf("%w: padding output value %d", ErrDustOutput, value)
+	}
+	totalOut := value * int64(request.Count)
+	tx.AddTxOut(wire.NewTxOut(0, changePkScript))
+
+	if err := sign(tx, privateKeys, prevOutFetcher, multisigs); err != nil {
+		return nil, err
+	}
+	fee := request.FeeRate.FeeForWeight(blockchain.GetTransactionWeight(btcutil.NewTx(tx)))
+	change := totalIn - totalOut - fee
+	tx.TxOut[len(tx.TxOut)-1].Value = change
+	if change <= 0 || mempool.IsDust(tx.TxOut[len(tx.TxOut)-1], mempool.DefaultMinRelayTxFee) {
+		tx.TxOut = tx.TxOut[:len(tx.TxOut)-1]
+		change = 0
+		if totalIn-totalOut < request.FeeRate.FeeForWeight(blockchain.GetTransactionWeight(btcutil.NewTx(tx))) {
+			return nil, ErrInsufficientBalance
+		}
+	}
+	if err := sign(tx, privateKeys, prevOutFetcher, multisigs); err != nil {
+		return nil, err
+	}
+	if err := request.FeeGuard.orDefault().checkTx("padding", 0, tx, totalIn); err != nil {
+		return nil, err
+	}
+
+	txHex, err := getTxHex(tx)
+	if err != nil {
+		return nil, err
+	}
+	txId := tx.TxHash().String()
+	utxos := make([]*PrevOutput, request.Count)
+	for i := range utxos {
+		utxos[i] = &PrevOutput{
+			TxId:      txId,
+			VOut:      uint32(i),
+			Amount:    value,
+			Address:   request.Address,
+			IsPadding: true,
+		}
+	}
+	return &DummyUTXOs{
+		Tx:     txHex,
+		Fee:    totalIn - totalOut - change,
+		Change: change,
+		Utxos:  utxos,
+	}, nil
+}
+
+func checkNotPadding(prevOutputs []*PrevOutput) error {
+	for _, prevOutput := range prevOutputs {
+		if prevOutput.IsPadding {
+			return fmt.Errorf("%w: %s:%d", ErrPaddingUtxo, prevOutput.TxId, prevOutput.VOut)
+		}
+	}
+	return nil
+}
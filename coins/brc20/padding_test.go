@@ -0,0 +1,77 @@
+package brc20
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/btcsuite/btcd/chaincfg"
+)
+
+func TestPrepareDummyUTXOs(t *testing.T) {
+	network := &chaincfg.TestNet3Params
+
+	request := &DummyUTXORequest{
+		FundingInputs: []*PrevOutput{{
+			TxId:       "25b9d08a26c8d47795301dd47a861cff0459d14f27fbd41cffaca17d9aa20f87",
+			VOut:       0,
+			Amount:     249352,
+			Address:    "tb1qtsq9c4fje6qsmheql8gajwtrrdrs38kdzeersc",
+			PrivateKey: "cPnvkvUYyHcSSS26iD1dkrJdV7k1RoUqJLhn3CYxpo398PdLVE22",
+		}},
+		Address:       "tb1pklh8lqax5l7m2ycypptv2emc4gata2dy28svnwcp9u32wlkenvsspcvhsr",
+		Count:         3,
+		ChangeAddress: "tb1qtsq9c4fje6qsmheql8gajwtrrdrs38kdzeersc",
+		FeeRate:       2,
+	}
+	result, err := PrepareDummyUTXOs(request, network)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx, err := newTxFromHex(result.Tx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tx.TxOut) != 4 || len(result.Utxos) != 3 {
+		t.Fatalf("unexpected outputs %d, utxos %d", len(tx.TxOut), len(result.Utxos))
+	}
+	for i, utxo := range result.Utxos {
+		if !utxo.IsPadding || utxo.TxId != tx.TxHash().String() || utxo.VOut != uint32(i) ||
+			utxo.Amount != DefaultPaddingValue || tx.TxOut[i].Value != DefaultPaddingValue {
+			t.Fatalf("unexpected padding utxo %+v", utxo)
+		}
+	}
+	if result.Change != tx.TxOut[3].Value || result.Fee != 249352-3*DefaultPaddingValue-result.Change {
+		t.Fatalf("fee %d, change %d", result.Fee, result.Change)
+	}
+
+	for feeRate, want := range map[FeeRate]error{0: ErrInvalidFeeRate, -1: ErrInvalidFeeRate, MaxFeeRate + 1: ErrAbsurdFeeRate} {
+		badRate := *request
+		badRate.FeeRate = feeRate
+		if _, err := PrepareDummyUTXOs(&badRate, network); !errors.Is(err, want) {
+			t.Fatalf("fee rate %v: expected %v, got %v", feeRate, want, err)
+		}
+	}
+
+	// padding utxos are not spent by accident
+	utxo := result.Utxos[0]
+	_, err = Transfer([]*TxInput{{
+		TxId:       utxo.TxId,
+		VOut:       utxo.VOut,
+		Amount:     utxo.Amount,
+		Address:    utxo.Address,
+		PrivateKey: "cPnvkvUYyHcSSS26iD1dkrJdV7k1RoUqJLhn3CYxpo398PdLVE22",
+		IsPadding:  utxo.IsPadding,
+	}}, []*TxOutput{{Address: "tb1qtsq9c4fje6qsmheql8gajwtrrdrs38kdzeersc", Amount: 330}}, network)
+	if !errors.Is(err, ErrPaddingUtxo) {
+		t.Fatal(err)
+	}
+	inscriptionRequest := newTestInscriptionRequest()
+	inscriptionRequest.CommitTxPrevOutputList = append(inscriptionRequest.CommitTxPrevOutputList, utxo)
+	if _, err = Inscribe(network, inscriptionRequest); !errors.Is(err, ErrPaddingUtxo) {
+		t.Fatal(err)
+	}
+	request.FundingInputs = result.Utxos
+	if _, err = PrepareDummyUTXOs(request, network); !errors.Is(err, ErrPaddingUtxo) {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,401 @@
+package brc20
+
+import (
+	"fmt"
+
+	"github.com/btcsuite/btcd/blockchain"
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+)
+
+// policy rules, named after bitcoin core's reject reasons
+const (
+	PolicyVersion            = "version"
+	PolicyTxSizeSmall        = "tx-size-small"
+	PolicyTxSize             = "tx-size"
+	PolicySigOps             = "bad-txns-too-many-sigops"
+	PolicyScriptSigSize      = "scriptsig-size"
+	PolicyScriptSigPushOnly  = "scriptsig-not-pushonly"
+	PolicyScriptPubKey       = "scriptpubkey"
+	PolicyMultiOpReturn      = "multi-op-return"
+	PolicyDust               = "dust"
+	PolicyWitnessNonStandard = "bad-witness-nonstandard"
+	PolicyMempoolChain       = "too-long-mempool-chain"
+)
+
+// p2wsh witness limits of bitcoin core's IsWitnessStandard
+const (
+	maxStandardP2WSHScriptSize = 3600
+	maxStandardP2WSHStackItems = 100
+	maxStandardP2WSHStackItem  = 80
+)
+
+// Policy holds the mempool standardness rules a transaction must meet to be
+// relayed. A zero limit is not checked.
+type Policy struct {
+	MaxTxVersion        int32   `json:"maxTxVersion"`
+	MinTxNonWitnessSize int     `json:"minTxNonWitnessSize"`
+	MaxTxWeight         int64   `json:"maxTxWeight"`
+	MaxTxSigOpsCost     int64   `json:"maxTxSigOpsCost"`
+	MaxScriptSigSize    int     `json:"maxScriptSigSize"`
+	DustRelayFeeRate    FeeRate `json:"dustRelayFeeRate"`
+	// MaxOpReturnSize is the size of an OP_RETURN output script, OP_RETURN included
+	MaxOpReturnSize    int `json:"maxOpReturnSize"`
+	MaxOpReturnOutputs int `json:"maxOpReturnOutputs"`
+	// ancestor and descendant limits of the unconfirmed txs checked together, counts
+	// include the tx itself and sizes are in vbytes
+	MaxAncestors      int   `json:"maxAncestors"`
+	MaxAncestorSize   int64 `json:"maxAncestorSize"`
+	MaxDescendants    int   `json:"maxDescendants"`
+	MaxDescendantSize int64 `json:"maxDescendantSize"`
+}
+
+// DefaultPolicy returns the default policy of bitcoin core 27, used when no Policy
+// is given. Core 27 predates TRUC (v3) transactions and the larger OP_RETURN
+// defaults of core 30, so v3 transactions are not standard and a single 83 byte
+// OP_RETURN output is allowed.
+func DefaultPolicy() *Policy {
+	return &Policy{
+		MaxTxVersion:        2,
+		MinTxNonWitnessSize: 65,
+		MaxTxWeight:         MaxStandardTxWeight,
+		MaxTxSigOpsCost:     blockchain.MaxBlockSigOpsCost / 5,
+		MaxScriptSigSize:    1650,
+		DustRelayFeeRate:    3,
+		MaxOpReturnSize:     83,
+		MaxOpReturnOutputs:  1,
+		MaxAncestors:        25,
+		MaxAncestorSize:     101000,
+		MaxDescendants:      25,
+		MaxDescendantSize:   101000,
+	}
+}
+
+type PolicyViolation struct {
+	TxType string `json:"txType"`
+	Index  int    `json:"index"`
+	Rule   string `json:"rule"`
+	Detail string `json:"detail"`
+}
+
+func (v *PolicyViolation) Error() string {
+	return fmt.Sprintf("%s tx(index %d) violates %s: %s", v.TxType, v.Index, v.Rule, v.Detail)
+}
+
+type policyTx struct {
+	txType string
+	index  int
+	tx     *wire.MsgTx
+}
+
+// PolicyCheck returns every policy violation of the commit and reveal txs of
+// request, with policy, or DefaultPolicy when nil. The reveal txs count as
+// descendants of the commit tx. Without request, the commit inputs are taken as
+// unknown and the checks needing their outputs are skipped for them.
+func PolicyCheck(txs *InscribeTxs, request *InscriptionRequest, network *chaincfg.Params, policy *Policy) ([]*PolicyViolation, error) {
+	if txs == nil {
+		return nil, nil
+	}
+	var ptxs []*policyTx
+	if txs.CommitTx != "" {
+		commitTx, err := newTxFromHex(txs.CommitTx)
+		if err != nil {
+			return nil, err
+		}
+		ptxs = append(ptxs, &policyTx{txType: "commit", tx: commitTx})
+	}
+	for i, revealTxHex := range txs.RevealTxs {
+		revealTx, err := newTxFromHex(revealTxHex)
+		if err != nil {
+			return nil, err
+		}
+		ptxs = append(ptxs, &policyTx{txType: "reveal", index: i, tx: revealTx})
+	}
+	prevOuts := txscript.NewMultiPrevOutFetcher(nil)
+	if request != nil {
+		for _, prevOutput := range request.CommitTxPrevOutputList {
+			if err := addPrevOutput(prevOuts, prevOutput.TxId, prevOutput.VOut, prevOutput.Amount, prevOutput.Address, network); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return policy.check(ptxs, prevOuts), nil
+}
+
+// PolicyCheckTransfer returns every policy violation of txHex, a tx from Transfer
+// or TransferWithChange spending ins, with policy, or DefaultPolicy when nil.
+func PolicyCheckTransfer(txHex string, ins []*TxInput, network *chaincfg.Params, policy *Policy) ([]*PolicyViolation, error) {
+	tx, err := newTxFromHex(txHex)
+	if err != nil {
+		return nil, err
+	}
+	prevOuts := txscript.NewMultiPrevOutFetcher(nil)
+	for _, in := range ins {
+		if err = addPrevOutput(prevOuts, in.TxId, in.VOut, in.Amount, in.Address, network); err != nil {
+			return nil, err
+		}
+	}
+	return policy.check([]*policyTx{{txType: "transfer", tx: tx}}, prevOuts), nil
+}
+
+func addPrevOutput(prevOuts *txscript.MultiPrevOutFetcher, txId string, vOut uint32, amount int64, address string, network *chaincfg.Params) error {
+	hash, err := chainhash.NewHashFromStr(txId)
+	if err != nil {
+		return err
+	}
+	pkScript, err := AddrToPkScript(address, network)
+	if err != nil {
+		return err
+	}
+	prevOuts.AddPrevOut(*wire.NewOutPoint(hash, vOut), wire.NewTxOut(amount, pkScript))
+	return nil
+}
+
+func (policy *Policy) check(ptxs []*policyTx, prevOuts *txscript.MultiPrevOutFetcher) []*PolicyViolation {
+	if policy == nil {
+		policy = DefaultPolicy()
+	}
+	for _, ptx := range ptxs {
+		txHash := ptx.tx.TxHash()
+		for i, out := range ptx.tx.TxOut {
+			prevOuts.AddPrevOut(*wire.NewOutPoint(&txHash, uint32(i)), out)
+		}
+	}
+	var violations []*PolicyViolation
+	for _, ptx := range ptxs {
+		violations = append(violations, policy.checkTx(ptx, prevOuts)...)
+	}
+	return append(violations, policy.checkPackage(ptxs)...)
+}
+
+func (policy *Policy) checkTx(ptx *policyTx, prevOuts txscript.PrevOutputFetcher) []*PolicyViolation {
+	var violations []*PolicyViolation
+	violate := func(rule, format string, args ...interface{}) {
+		violations = append(violations, &PolicyViolation{
+			TxType: ptx.txType,
+			Index:  ptx.index,
+			Rule:   rule,
+			Detail: fmt.Sprintf(format, args...),
+		})
+	}
+	tx := ptx.tx
+
+	if policy.MaxTxVersion > 0 && (tx.Version < 1 || tx.Version > policy.MaxTxVersion) {
+		violate(PolicyVersion, "version %d", tx.Version)
+	}
+	if policy.MinTxNonWitnessSize > 0 && tx.SerializeSizeStripped() < policy.MinTxNonWitnessSize {
+		violate(PolicyTxSizeSmall, "non-witness size %d below %d", tx.SerializeSizeStripped(), policy.MinTxNonWitnessSize)
+	}
+	weight := blockchain.GetTransactionWeight(btcutil.NewTx(tx))
+	if policy.MaxTxWeight > 0 && weight > policy.MaxTxWeight {
+		violate(PolicyTxSize, "weight %d above %d", weight, policy.MaxTxWeight)
+	}
+	if sigOpsCost := txSigOpsCost(tx, prevOuts); policy.MaxTxSigOpsCost > 0 && sigOpsCost > policy.MaxTxSigOpsCost {
+		violate(PolicySigOps, "sigops cost %d above %d", sigOpsCost, policy.MaxTxSigOpsCost)
+	}
+
+	for i, in := range tx.TxIn {
+		if policy.MaxScriptSigSize > 0 && len(in.SignatureScript) > policy.MaxScriptSigSize {
+			violate(PolicyScriptSigSize, "input %d scriptSig size %d above %d", i, len(in.SignatureScript), policy.MaxScriptSigSize)
+		}
+		if !txscript.IsPushOnlyScript(in.SignatureScript) {
+			violate(PolicyScriptSigPushOnly, "input %d", i)
+		}
+		if prevOut := prevOuts.FetchPrevOutput(in.PreviousOutPoint); prevOut != nil {
+			if reason := witnessNonStandard(in, prevOut.PkScript); reason != "" {
+				violate(PolicyWitnessNonStandard, "input %d %s", i, reason)
+			}
+		}
+	}
+
+	opReturnOutputs := 0
+	for i, out := range tx.TxOut {
+		if len(out.PkScript) > 0 && out.PkScript[0] == txscript.OP_RETURN {
+			opReturnOutputs++
+			if !txscript.IsPushOnlyScript(out.PkScript[1:]) {
+				violate(PolicyScriptPubKey, "output %d OP_RETURN data is not push only", i)
+			} else if policy.MaxOpReturnSize > 0 && len(out.PkScript) > policy.MaxOpReturnSize {
+				violate(PolicyScriptPubKey, "output %d OP_RETURN size %d above %d", i, len(out.PkScript), policy.MaxOpReturnSize)
+			}
+			continue
+		}
+		if !isStandardPkScript(out.PkScript) {
+			violate(PolicyScriptPubKey, "output %d script %x", i, out.PkScript)
+			continue
+		}
+		if threshold := policy.dustThreshold(out); out.Value < threshold {
+			violate(PolicyDust, "output %d value %d below %d", i, out.Value, threshold)
+		}
+	}
+	if policy.MaxOpReturnOutputs > 0 && opReturnOutputs > policy.MaxOpReturnOutputs {
+		violate(PolicyMultiOpReturn, "%d OP_RETURN outputs above %d", opReturnOutputs, policy.MaxOpReturnOutputs)
+	}
+	return violations
+}
+
+// checkPackage checks the unconfirmed ancestors and descendants of each tx among
+// ptxs. Inputs spending other txs are taken as confirmed.
+func (policy *Policy) checkPackage(ptxs []*policyTx) []*PolicyViolation {
+	byHash := make(map[chainhash.Hash]int)
+	for i, ptx := range ptxs {
+		byHash[ptx.tx.TxHash()] = i
+	}
+	parents := make([]map[int]bool, len(ptxs))
+	for i, ptx := range ptxs {
+		parents[i] = make(map[int]bool)
+		for _, in := range ptx.tx.TxIn {
+			if parent, ok := byHash[in.PreviousOutPoint.Hash]; ok {
+				parents[i][parent] = true
+			}
+		}
+	}
+
+	// ancestors[i][j] when j is i or an ancestor of it
+	ancestors := make([]map[int]bool, len(ptxs))
+	var collect func(i int) map[int]bool
+	collect = func(i int) map[int]bool {
+		if ancestors[i] == nil {
+			ancestors[i] = map[int]bool{i: true}
+			for parent := range parents[i] {
+				for ancestor := range collect(parent) {
+					ancestors[i][ancestor] = true
+				}
+			}
+		}
+		return ancestors[i]
+	}
+	vsizes := make([]int64, len(ptxs))
+	for i, ptx := range ptxs {
+		collect(i)
+		vsizes[i] = (blockchain.GetTransactionWeight(btcutil.NewTx(ptx.tx)) + blockchain.WitnessScaleFactor - 1) /
+			blockchain.WitnessScaleFactor
+	}
+
+	var violations []*PolicyViolation
+	for i, ptx := range ptxs {
+		var ancestorCount, descendantCount int
+		var ancestorSize, descendantSize int64
+		for j := range ptxs {
+			if ancestors[i][j] {
+				ancestorCount++
+				ancestorSize += vsizes[j]
+			}
+			if ancestors[j][i] {
+				descendantCount++
+				descendantSize += vsizes[j]
+			}
+		}
+		var details []string
+		if policy.MaxAncestors > 0 && ancestorCount > policy.MaxAncestors {
+			details = append(details, fmt.Sprintf("%d ancestors above %d", ancestorCount, policy.MaxAncestors))
+		}
+		if policy.MaxAncestorSize > 0 && ancestorSize > policy.MaxAncestorSize {
+			details = append(details, fmt.Sprintf("ancestor size %d above %d", ancestorSize, policy.MaxAncestorSize))
+		}
+		if policy.MaxDescendants > 0 && descendantCount > policy.MaxDescendants {
+			details = append(details, fmt.Sprintf("%d descendants above %d", descendantCount, policy.MaxDescendants))
+		}
+		if policy.MaxDescendantSize > 0 && descendantSize > policy.MaxDescendantSize {
+			details = append(details, fmt.Sprintf("descendant size %d above %d", descendantSize, policy.MaxDescendantSize))
+		}
+		for _, detail := range details {
+			violations = append(violations, &PolicyViolation{
+				TxType: ptx.txType,
+				Index:  ptx.index,
+				Rule:   PolicyMempoolChain,
+				Detail: detail,
+			})
+		}
+	}
+	return violations
+}
+
+// dustThreshold is bitcoin core's GetDustThreshold: the value below which spending
+// out costs more than a third of it at the dust relay fee rate.
+func (policy *Policy) dustThreshold(out *wire.TxOut) int64 {
+	if policy.DustRelayFeeRate <= 0 {
+		return 0
+	}
+	size := int64(out.SerializeSize())
+	if txscript.IsWitnessProgram(out.PkScript) {
+		size += 32 + 4 + 1 + 107/blockchain.WitnessScaleFactor + 4
+	} else {
+		size += 32 + 4 + 1 + 107 + 4
+	}
+	return policy.DustRelayFeeRate.FeeForVSize(size)
+}
+
+func isStandardPkScript(pkScript []byte) bool {
+	switch txscript.GetScriptClass(pkScript) {
+	case txscript.PubKeyTy, txscript.PubKeyHashTy, txscript.ScriptHashTy, txscript.WitnessV0PubKeyHashTy,
+		txscript.WitnessV0ScriptHashTy, txscript.WitnessV1TaprootTy, txscript.WitnessUnknownTy:
+		return true
+	case txscript.MultiSigTy:
+		pubKeys, threshold, err := txscript.CalcMultiSigStats(pkScript)
+		return err == nil && pubKeys <= 3 && threshold >= 1 && threshold <= pubKeys
+	default:
+		return false
+	}
+}
+
+// witnessNonStandard returns why the witness of in, which spends pkScript, is not
+// standard, or "" when it is.
+func witnessNonStandard(in *wire.TxIn, pkScript []byte) string {
+	if len(in.Witness) == 0 {
+		return ""
+	}
+	if txscript.IsPayToScriptHash(pkScript) {
+		pushes, err := txscript.PushedData(in.SignatureScript)
+		if err != nil || len(pushes) == 0 {
+			return ""
+		}
+		pkScript = pushes[len(pushes)-1]
+	}
+	witness := in.Witness
+	switch {
+	case txscript.IsPayToWitnessScriptHash(pkScript):
+		script := witness[len(witness)-1]
+		if len(script) > maxStandardP2WSHScriptSize {
+			return fmt.Sprintf("witness script size %d above %d", len(script), maxStandardP2WSHScriptSize)
+		}
+		if len(witness)-1 > maxStandardP2WSHStackItems {
+			return fmt.Sprintf("%d witness items above %d", len(witness)-1, maxStandardP2WSHStackItems)
+		}
+		for _, item := range witness[:len(witness)-1] {
+			if len(item) > maxStandardP2WSHStackItem {
+				return fmt.Sprintf("witness item size %d above %d", len(item), maxStandardP2WSHStackItem)
+			}
+		}
+	case txscript.IsPayToTaproot(pkScript):
+		if len(witness) >= 2 && len(witness[len(witness)-1]) > 0 && witness[len(witness)-1][0] == txscript.TaprootAnnexTag {
+			return "annex"
+		}
+	}
+	return ""
+}
+
+// txSigOpsCost is the sigops cost of tx as blockchain.GetSigOpCost counts it, with
+// the spent outputs from prevOuts.
+func txSigOpsCost(tx *wire.MsgTx, prevOuts txscript.PrevOutputFetcher) int64 {
+	cost := 0
+	for _, in := range tx.TxIn {
+		cost += txscript.GetSigOpCount(in.SignatureScript) * blockchain.WitnessScaleFactor
+	}
+	for _, out := range tx.TxOut {
+		cost += txscript.GetSigOpCount(out.PkScript) * blockchain.WitnessScaleFactor
+	}
+	for _, in := range tx.TxIn {
+		prevOut := prevOuts.FetchPrevOutput(in.PreviousOutPoint)
+		if prevOut == nil {
+			continue
+		}
+		if txscript.IsPayToScriptHash(prevOut.PkScript) {
+			cost += txscript.GetPreciseSigOpCount(in.SignatureScript, prevOut.PkScript, true) * blockchain.WitnessScaleFactor
+		}
+		cost += txscript.GetWitnessSigOpCount(in.SignatureScript, prevOut.PkScript, in.Witness)
+	}
+	return int64(cost)
+}
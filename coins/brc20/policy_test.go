@@ -0,0 +1,109 @@
+package brc20
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/txscript"
+)
+
+func TestPolicyCheck(t *testing.T) {
+	network := &chaincfg.TestNet3Params
+	request := newTestInscriptionRequest()
+	txs, err := Inscribe(network, request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	violations, err := PolicyCheck(txs, request, network, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(violations) > 0 {
+		t.Fatalf("unexpected violations %v", violations)
+	}
+
+	// the commit tx of 25 reveals has 26 descendants
+	data := request.InscriptionDataList[0]
+	request.InscriptionDataList = nil
+	for i := 0; i < 25; i++ {
+		request.InscriptionDataList = append(request.InscriptionDataList, data)
+	}
+	if txs, err = Inscribe(network, request); err != nil {
+		t.Fatal(err)
+	}
+	if violations, err = PolicyCheck(txs, request, network, nil); err != nil {
+		t.Fatal(err)
+	}
+	if len(violations) != 1 || violations[0].Rule != PolicyMempoolChain || violations[0].TxType != "commit" {
+		t.Fatalf("expected a too-long-mempool-chain violation of the commit tx, got %v", violations)
+	}
+	policy := DefaultPolicy()
+	policy.MaxDescendants = 0
+	if violations, err = PolicyCheck(txs, request, network, policy); err != nil {
+		t.Fatal(err)
+	}
+	if len(violations) > 0 {
+		t.Fatalf("unexpected violations %v", violations)
+	}
+	if DefaultPolicy().MaxDescendants != 25 {
+		t.Fatal("the default policy was changed")
+	}
+
+	// the commit inputs are unknown without the request
+	if violations, err = PolicyCheck(txs, nil, network, policy); err != nil {
+		t.Fatal(err)
+	}
+	if len(violations) > 0 {
+		t.Fatalf("unexpected violations %v", violations)
+	}
+}
+
+func TestPolicyCheckTransfer(t *testing.T) {
+	network := &chaincfg.TestNet3Params
+	ins := []*TxInput{{
+		TxId:       "25b9d08a26c8d47795301dd47a861cff0459d14f27fbd41cffaca17d9aa20f87",
+		VOut:       uint32(0),
+		Amount:     int64(100000),
+		Address:    "tb1pklh8lqax5l7m2ycypptv2emc4gata2dy28svnwcp9u32wlkenvsspcvhsr",
+		PrivateKey: "cPnvkvUYyHcSSS26iD1dkrJdV7k1RoUqJLhn3CYxpo398PdLVE22",
+	}}
+	// OP_RETURN outputs of more than 80 bytes of data are built by hand
+	longScript, err := txscript.NewScriptBuilder().AddOp(txscript.OP_RETURN).
+		AddData(bytes.Repeat([]byte{1}, 50)).AddData(bytes.Repeat([]byte{2}, 50)).Script()
+	if err != nil {
+		t.Fatal(err)
+	}
+	shortData, err := NullDataOutput([]byte("brc-20"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	outs := []*TxOutput{
+		{Address: "tb1qtsq9c4fje6qsmheql8gajwtrrdrs38kdzeersc", Amount: 294},
+		{Address: "tb1pklh8lqax5l7m2ycypptv2emc4gata2dy28svnwcp9u32wlkenvsspcvhsr", Amount: 329},
+		{PkScript: longScript},
+		shortData,
+	}
+	txHex, err := Transfer(ins, outs, network)
+	if err != nil {
+		t.Fatal(err)
+	}
+	violations, err := PolicyCheckTransfer(txHex, ins, network, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rules := make(map[string]int)
+	for _, violation := range violations {
+		rules[violation.Rule]++
+	}
+	// the p2wpkh output is at its dust threshold, the p2tr one 1 sat below
+	expected := map[string]int{PolicyDust: 1, PolicyScriptPubKey: 1, PolicyMultiOpReturn: 1}
+	if len(rules) != len(expected) {
+		t.Fatalf("expected %v, got %v", expected, violations)
+	}
+	for rule, count := range expected {
+		if rules[rule] != count {
+			t.Fatalf("expected %v, got %v", expected, violations)
+		}
+	}
+}
@@ -0,0 +1,441 @@
+package brc20
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/btcec/v2/schnorr"
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/btcutil/psbt"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+)
+
+var (
+	ErrInvalidPSBT  = errors.New("invalid psbt")
+	ErrPSBTMismatch = errors.New("psbts spend different transactions")
+	ErrMissingUtxo  = errors.New("psbt input utxo missing")
+	ErrUtxoMismatch = errors.New("psbt input utxo does not match its outpoint")
+)
+
+// Signer holds the keys SignPSBT signs with.
+type Signer interface {
+	// PrivateKey returns the key spending pkScript, or nil if the signer holds none.
+	// For a multisig input it is asked for "<pubkey> OP_CHECKSIG" of every key in
+	// the script.
+	PrivateKey(pkScript []byte) (*btcec.PrivateKey, error)
+}
+
+// KeySigner is a Signer for WIF keys. It signs for the p2pk, p2pkh, p2wpkh,
+// p2sh-p2wpkh and key path p2tr outputs of each key.
+type KeySigner struct {
+	keys []*btcec.PrivateKey
+}
+
+func NewKeySigner(privateKeys ...string) (*KeySigner, error) {
+	signer := &KeySigner{}
+	for _, privateKey := range privateKeys {
+		wif, err := btcutil.DecodeWIF(privateKey)
+		if err != nil {
+			return nil, err
+		}
+		signer.keys = append(signer.keys, wif.PrivKey)
+	}
+	return signer, nil
+}
+
+func (signer *KeySigner) PrivateKey(pkScript []byte) (*btcec.PrivateKey, error) {
+	for _, key := range signer.keys {
+		pubKeyHash := btcutil.Hash160(key.PubKey().SerializeCompressed())
+		p2wpkh, err := PayToWitnessPubKeyHashScript(pubKeyHash)
+		if err != nil {
+			return nil, err
+		}
+		p2pkh, err := PayToPubKeyHashScript(pubKeyHash)
+		if err != nil {
+			return nil, err
+		}
+		p2sh, err := payToScriptHashScript(p2wpkh)
+		if err != nil {
+			return nil, err
+		}
+		p2pk, err := txscript.NewScriptBuilder().AddData(key.PubKey().SerializeCompressed()).
+			AddOp(txscript.OP_CHECKSIG).Script()
+		if err != nil {
+			return nil, err
+		}
+		p2tr, err := txscript.NewScriptBuilder().AddOp(txscript.OP_1).
+			AddData(schnorr.SerializePubKey(txscript.ComputeTaprootKeyNoScript(key.PubKey()))).Script()
+		if err != nil {
+			return nil, err
+		}
+		for _, script := range [][]byte{p2tr, p2wpkh, p2sh, p2pkh, p2pk} {
+			if bytes.Equal(pkScript, script) {
+				return key, nil
+			}
+		}
+	}
+	return nil, nil
+}
+
+// SignPSBT signs every input of packet that signer holds a key for, with the sighash
+// type declared in the input, SIGHASH_ALL (SIGHASH_DEFAULT for taproot) if none.
+// Finalized inputs and inputs the signer already signed are left alone. packet may be
+// base64 or hex, and the result uses the same encoding.
+func SignPSBT(packet string, signer Signer) (string, error) {
+	bp, isHex, err := decodePSBT(packet)
+	if err != nil {
+		return "", err
+	}
+	prevOuts, complete, err := psbtPrevOuts(bp)
+	if err != nil {
+		return "", err
+	}
+	updater, err := psbt.NewUpdater(bp)
+	if err != nil {
+		return "", err
+	}
+	for i, pInput := range bp.Inputs {
+		if isFinalizedInput(pInput) || len(pInput.TaprootKeySpendSig) > 0 {
+			continue
+		}
+		prevOut := prevOuts.FetchPrevOutput(bp.UnsignedTx.TxIn[i].PreviousOutPoint)
+		if prevOut == nil {
+			continue
+		}
+		hashType := pInput.SighashType
+		if hashType == txscript.SigHashDefault {
+			hashType = txscript.SigHashAll
+		}
+		if len(pInput.WitnessScript) > 0 || len(pInput.RedeemScript) > 0 && !txscript.IsWitnessProgram(pInput.RedeemScript) {
+			if err = signMultisigPInput(updater, i, signer, prevOut.PkScript, prevOuts, hashType); err != nil {
+				return "", fmt.Errorf("sign input %d: %w", i, err)
+			}
+			continue
+		}
+		privKey, err := signer.PrivateKey(prevOut.PkScript)
+		if err != nil {
+			return "", err
+		}
+		if privKey == nil || hasPartialSigFor(pInput, privKey.PubKey().SerializeCompressed()) {
+			continue
+		}
+		if txscript.IsPayToTaproot(prevOut.PkScript) && !complete {
+			// the taproot sighash commits to every spent output
+			return "", fmt.Errorf("%w: input %d is taproot and other inputs lack utxos", ErrMissingUtxo, i)
+		}
+		if err = signPInput(updater, i, privKey, prevOut.PkScript, prevOut.Value, prevOuts, hashType,
+			pInput.TaprootMerkleRoot); err != nil {
+			return "", fmt.Errorf("sign input %d: %w", i, err)
+		}
+	}
+	return encodePSBT(bp, isHex)
+}
+
+// CombinePSBTs merges the signatures and other input fields of PSBTs of the same
+// unsigned transaction, e.g. each signed by a different party. The result uses the
+// encoding of the first packet.
+func CombinePSBTs(packets ...string) (string, error) {
+	if len(packets) == 0 {
+		return "", fmt.Errorf("%w: nothing to combine", ErrInvalidPSBT)
+	}
+	combined, isHex, err := decodePSBT(packets[0])
+	if err != nil {
+		return "", err
+	}
+	txHash := combined.UnsignedTx.TxHash()
+	for _, packet := range packets[1:] {
+		bp, _, err := decodePSBT(packet)
+		if err != nil {
+			return "", err
+		}
+		if bp.UnsignedTx.TxHash() != txHash {
+			return "", fmt.Errorf("%w: %s and %s", ErrPSBTMismatch, txHash, bp.UnsignedTx.TxHash())
+		}
+		for i := range bp.Inputs {
+			combinePInput(&combined.Inputs[i], &bp.Inputs[i])
+		}
+		for i := range bp.Outputs {
+			out, other := &combined.Outputs[i], &bp.Outputs[i]
+			if out.RedeemScript == nil {
+				out.RedeemScript = other.RedeemScript
+			}
+			if out.WitnessScript == nil {
+				out.WitnessScript = other.WitnessScript
+			}
+			if out.TaprootInternalKey == nil {
+				out.TaprootInternalKey = other.TaprootInternalKey
+			}
+		}
+	}
+	return encodePSBT(combined, isHex)
+}
+
+// FinalizePSBT builds the final scriptSig and witness of every input that is not
+// finalized yet. The result uses the encoding of packet.
+func FinalizePSBT(packet string) (string, error) {
+	bp, isHex, err := decodePSBT(packet)
+	if err != nil {
+		return "", err
+	}
+	for i := range bp.Inputs {
+		if err = finalizeInput(bp, i); err != nil {
+			return "", fmt.Errorf("finalize input %d: %w", i, err)
+		}
+	}
+	return encodePSBT(bp, isHex)
+}
+
+// ExtractTx returns the hex of the network transaction of a finalized PSBT, after
+// checking its fee against DefaultFeeGuard or the WithFeeGuard option, and its
+// scripts with the WithVerifyScripts option.
+func ExtractTx(packet string, opts ...TxOption) (string, error) {
+	options := newTxOptions(opts)
+	bp, _, err := decodePSBT(packet)
+	if err != nil {
+		return "", err
+	}
+	tx, err := psbt.Extract(bp)
+	if err != nil {
+		return "", err
+	}
+	prevOuts, complete, err := psbtPrevOuts(bp)
+	if err != nil {
+		return "", err
+	}
+	if !complete {
+		return "", fmt.Errorf("%w: the fee cannot be checked", ErrMissingUtxo)
+	}
+	inputValue := int64(0)
+	for _, in := range tx.TxIn {
+		inputValue += prevOuts.FetchPrevOutput(in.PreviousOutPoint).Value
+	}
+	if err = options.feeGuard.checkTx("psbt", 0, tx, inputValue); err != nil {
+		return "", err
+	}
+	if options.verifyScripts {
+		if err = verifyTx("psbt", 0, tx, prevOuts); err != nil {
+			return "", err
+		}
+	}
+	return getTxHex(tx)
+}
+
+// decodePSBT decodes a base64 or hex PSBT and reports whether it was hex.
+func decodePSBT(packet string) (*psbt.Packet, bool, error) {
+	raw, err := hex.DecodeString(packet)
+	isHex := err == nil
+	if !isHex {
+		if raw, err = base64.StdEncoding.DecodeString(packet); err != nil {
+			return nil, false, fmt.Errorf("%w: neither base64 nor hex", ErrInvalidPSBT)
+		}
+	}
+	bp, err := psbt.NewFromRawBytes(bytes.NewReader(raw), false)
+	if err != nil {
+		return nil, false, fmt.Errorf("%w: %v", ErrInvalidPSBT, err)
+	}
+	return bp, isHex, nil
+}
+
+func encodePSBT(bp *psbt.Packet, isHex bool) (string, error) {
+	if !isHex {
+		return bp.B64Encode()
+	}
+	var buf bytes.Buffer
+	if err := bp.Serialize(&buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf.Bytes()), nil
+}
+
+// psbtPrevOuts returns the spent outputs known to bp, and whether every input has one.
+func psbtPrevOuts(bp *psbt.Packet) (*txscript.MultiPrevOutFetcher, bool, error) {
+	prevOuts := txscript.NewMultiPrevOutFetcher(nil)
+	complete := true
+	for i := range bp.Inputs {
+		outPoint := bp.UnsignedTx.TxIn[i].PreviousOutPoint
+		prevOut, err := pInputPrevOut(&bp.Inputs[i], outPoint)
+		if err != nil {
+			return nil, false, fmt.Errorf("input %d: %w", i, err)
+		}
+		if prevOut == nil {
+			complete = false
+			continue
+		}
+		prevOuts.AddPrevOut(outPoint, prevOut)
+	}
+	return prevOuts, complete, nil
+}
+
+// pInputPrevOut returns the output at outPoint that pInput spends, or nil when the
+// input carries no utxo. A NonWitnessUtxo must be the tx of outPoint, or it could
+// misstate the amount that the segwit v0 sighash and the fee checks rely on.
+func pInputPrevOut(pInput *psbt.PInput, outPoint wire.OutPoint) (*wire.TxOut, error) {
+	if pInput.WitnessUtxo != nil {
+		return pInput.WitnessUtxo, nil
+	}
+	if pInput.NonWitnessUtxo == nil {
+		return nil, nil
+	}
+	if txHash := pInput.NonWitnessUtxo.TxHash(); txHash != outPoint.Hash {
+		return nil, fmt.Errorf("%w: non-witness utxo %s spent as %s", ErrUtxoMismatch, txHash, outPoint)
+	}
+	if int(outPoint.Index) >= len(pInput.NonWitnessUtxo.TxOut) {
+		return nil, fmt.Errorf("%w: non-witness utxo has no output %d", ErrUtxoMismatch, outPoint.Index)
+	}
+	return pInput.NonWitnessUtxo.TxOut[outPoint.Index], nil
+}
+
+// finalizeInput finalizes input i unless it already is, e.g. a script path spend
+// with extra witness elements.
+func finalizeInput(bp *psbt.Packet, i int) error {
+	if isFinalizedInput(bp.Inputs[i]) {
+		return nil
+	}
+	trimMultisigPartialSigs(&bp.Inputs[i])
+	return psbt.Finalize(bp, i)
+}
+
+// trimMultisigPartialSigs keeps the first threshold partial signatures of a
+// multisig input in script key order, since the finalizer puts every one of them
+// in the witness. Cosigners signing on their own may add more.
+func trimMultisigPartialSigs(pInput *psbt.PInput) {
+	script := pInput.WitnessScript
+	if len(script) == 0 {
+		script = pInput.RedeemScript
+	}
+	if isMultisig, err := txscript.IsMultisigScript(script); err != nil || !isMultisig {
+		return
+	}
+	_, threshold, err := txscript.CalcMultiSigStats(script)
+	if err != nil || len(pInput.PartialSigs) <= threshold {
+		return
+	}
+	pubKeys, err := txscript.PushedData(script)
+	if err != nil {
+		return
+	}
+	var sigs []*psbt.PartialSig
+	for _, pubKey := range pubKeys {
+		for _, sig := range pInput.PartialSigs {
+			if len(sigs) < threshold && bytes.Equal(sig.PubKey, pubKey) {
+				sigs = append(sigs, sig)
+				break
+			}
+		}
+	}
+	pInput.PartialSigs = sigs
+}
+
+func isFinalizedInput(pInput psbt.PInput) bool {
+	return len(pInput.FinalScriptSig) > 0 || len(pInput.FinalScriptWitness) > 0
+}
+
+// signMultisigPInput adds the signatures of the signer's keys to a multisig input.
+// Inputs with another script are left alone.
+func signMultisigPInput(updater *psbt.Updater, i int, signer Signer, pkScript []byte,
+	prevOuts txscript.PrevOutputFetcher, hashType txscript.SigHashType) error {
+	pInput := updater.Upsbt.Inputs[i]
+	multisig, err := newMultisigInput(pkScript, pInput.RedeemScript, pInput.WitnessScript, nil)
+	if err != nil {
+		return nil
+	}
+	for _, pubKey := range multisig.pubKeys {
+		p2pk, err := txscript.NewScriptBuilder().AddData(pubKey).AddOp(txscript.OP_CHECKSIG).Script()
+		if err != nil {
+			return err
+		}
+		privKey, err := signer.PrivateKey(p2pk)
+		if err != nil {
+			return err
+		}
+		if privKey != nil {
+			multisig.privateKeys = append(multisig.privateKeys, privKey)
+		}
+	}
+	return multisig.signPSBT(updater, i, prevOuts, hashType)
+}
+
+func hasPartialSigFor(pInput psbt.PInput, pubKey []byte) bool {
+	for _, sig := range pInput.PartialSigs {
+		if bytes.Equal(sig.PubKey, pubKey) {
+			return true
+		}
+	}
+	return false
+}
+
+// combinePInput copies the fields of other missing from in.
+func combinePInput(in, other *psbt.PInput) {
+	if isFinalizedInput(*in) {
+		return
+	}
+	if isFinalizedInput(*other) {
+		*in = *other
+		return
+	}
+	if in.NonWitnessUtxo == nil {
+		in.NonWitnessUtxo = other.NonWitnessUtxo
+	}
+	if in.WitnessUtxo == nil {
+		in.WitnessUtxo = other.WitnessUtxo
+	}
+	if in.SighashType == txscript.SigHashDefault {
+		in.SighashType = other.SighashType
+	}
+	if in.RedeemScript == nil {
+		in.RedeemScript = other.RedeemScript
+	}
+	if in.WitnessScript == nil {
+		in.WitnessScript = other.WitnessScript
+	}
+	if in.TaprootKeySpendSig == nil {
+		in.TaprootKeySpendSig = other.TaprootKeySpendSig
+	}
+	if in.TaprootInternalKey == nil {
+		in.TaprootInternalKey = other.TaprootInternalKey
+	}
+	if in.TaprootMerkleRoot == nil {
+		in.TaprootMerkleRoot = other.TaprootMerkleRoot
+	}
+	for _, sig := range other.PartialSigs {
+		found := false
+		for _, have := range in.PartialSigs {
+			found = found || bytes.Equal(have.PubKey, sig.PubKey)
+		}
+		if !found {
+			in.PartialSigs = append(in.PartialSigs, sig)
+		}
+	}
+	for _, sig := range other.TaprootScriptSpendSig {
+		found := false
+		for _, have := range in.TaprootScriptSpendSig {
+			found = found || have.EqualKey(sig)
+		}
+		if !found {
+			in.TaprootScriptSpendSig = append(in.TaprootScriptSpendSig, sig)
+		}
+	}
+	for _, leaf := range other.TaprootLeafScript {
+		found := false
+		for _, have := range in.TaprootLeafScript {
+			found = found || bytes.Equal(have.ControlBlock, leaf.ControlBlock) && bytes.Equal(have.Script, leaf.Script)
+		}
+		if !found {
+			in.TaprootLeafScript = append(in.TaprootLeafScript, leaf)
+		}
+	}
+	for _, derivation := range other.Bip32Derivation {
+		found := false
+		for _, have := range in.Bip32Derivation {
+			found = found || bytes.Equal(have.PubKey, derivation.PubKey)
+		}
+		if !found {
+			in.Bip32Derivation = append(in.Bip32Derivation, derivation)
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package brc20
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/btcutil/psbt"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+)
+
+func TestSignCombinePSBT(t *testing.T) {
+	network := &chaincfg.TestNet3Params
+
+	otherKey, err := btcec.NewPrivateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherWif, err := btcutil.NewWIF(otherKey, network, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherPkScript, err := PayToWitnessPubKeyHashScript(btcutil.Hash160(otherKey.PubKey().SerializeCompressed()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var pkScripts [][]byte
+	for _, address := range []string{
+		"tb1pklh8lqax5l7m2ycypptv2emc4gata2dy28svnwcp9u32wlkenvsspcvhsr",
+		"tb1qtsq9c4fje6qsmheql8gajwtrrdrs38kdzeersc",
+		"2NF33rckfiQTiE5Guk5ufUdwms8PgmtnEdc",
+	} {
+		pkScript, _ := AddrToPkScript(address, network)
+		pkScripts = append(pkScripts, pkScript)
+	}
+	pkScripts = append(pkScripts, otherPkScript)
+
+	var outPoints []*wire.OutPoint
+	var nSequences []uint32
+	for i := range pkScripts {
+		outPoints = append(outPoints, &wire.OutPoint{Hash: chainhash.Hash{byte(i + 1)}, Index: uint32(i)})
+		nSequences = append(nSequences, wire.MaxTxInSequenceNum)
+	}
+	payPkScript, _ := AddrToPkScript("tb1qtsq9c4fje6qsmheql8gajwtrrdrs38kdzeersc", network)
+	bp, err := psbt.New(outPoints, []*wire.TxOut{wire.NewTxOut(35000, payPkScript)}, txVersion, nLockTime, nSequences)
+	if err != nil {
+		t.Fatal(err)
+	}
+	prevOuts := txscript.NewMultiPrevOutFetcher(nil)
+	for i, pkScript := range pkScripts {
+		bp.Inputs[i].WitnessUtxo = wire.NewTxOut(10000, pkScript)
+		prevOuts.AddPrevOut(*outPoints[i], bp.Inputs[i].WitnessUtxo)
+	}
+	bp.Inputs[1].SighashType = txscript.SigHashAll | txscript.SigHashAnyOneCanPay
+	unsigned, err := encodePSBT(bp, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	signer, err := NewKeySigner("cPnvkvUYyHcSSS26iD1dkrJdV7k1RoUqJLhn3CYxpo398PdLVE22")
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherSigner, err := NewKeySigner(otherWif.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	signed, err := SignPSBT(unsigned, signer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = FinalizePSBT(signed); err == nil {
+		t.Fatal("finalized a psbt missing a signature")
+	}
+	otherSigned, err := SignPSBT(unsigned, otherSigner)
+	if err != nil {
+		t.Fatal(err)
+	}
+	combined, err := CombinePSBTs(signed, otherSigned)
+	if err != nil {
+		t.Fatal(err)
+	}
+	final, err := FinalizePSBT(combined)
+	if err != nil {
+		t.Fatal(err)
+	}
+	txHex, err := ExtractTx(final)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx, err := newTxFromHex(txHex)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range tx.TxIn {
+		if err := verifyInput(tx, i, prevOuts); err != nil {
+			t.Fatalf("input %d: %v", i, err)
+		}
+	}
+	if sig := tx.TxIn[1].Witness[0]; txscript.SigHashType(sig[len(sig)-1]) != bp.Inputs[1].SighashType {
+		t.Fatal("the declared sighash type must be used")
+	}
+
+	// signing again is a no-op, and base64 stays base64
+	b64, _ := encodePSBT(bp, false)
+	again, err := SignPSBT(b64, signer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again, err = SignPSBT(again, signer); err != nil {
+		t.Fatal(err)
+	}
+	if _, isHex, err := decodePSBT(again); err != nil || isHex {
+		t.Fatalf("expected base64, got hex %v, err %v", isHex, err)
+	}
+
+	bp.UnsignedTx.TxOut[0].Value--
+	mismatch, _ := encodePSBT(bp, false)
+	if _, err = CombinePSBTs(signed, mismatch); !errors.Is(err, ErrPSBTMismatch) {
+		t.Fatalf("expected ErrPSBTMismatch, got %v", err)
+	}
+	if _, err = SignPSBT("not a psbt", signer); !errors.Is(err, ErrInvalidPSBT) {
+		t.Fatalf("expected ErrInvalidPSBT, got %v", err)
+	}
+
+	// a non-witness utxo that is not the spent tx could misstate the input amount
+	forged := wire.NewMsgTx(txVersion)
+	forged.AddTxIn(wire.NewTxIn(&wire.OutPoint{Hash: chainhash.Hash{9}}, nil, nil))
+	forged.AddTxOut(wire.NewTxOut(1, pkScripts[0]))
+	bp.Inputs[0].WitnessUtxo, bp.Inputs[0].NonWitnessUtxo = nil, forged
+	forgedPacket, _ := encodePSBT(bp, false)
+	if _, err = SignPSBT(forgedPacket, signer); !errors.Is(err, ErrUtxoMismatch) {
+		t.Fatalf("expected ErrUtxoMismatch, got %v", err)
+	}
+}
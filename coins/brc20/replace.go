@@ -0,0 +1,143 @@
+package brc20
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/mempool"
+	"github.com/btcsuite/btcd/wire"
+)
+
+const (
+	// DefaultIncrementalRelayFeeRate is bitcoin core's default -incrementalrelayfee in sat/vB.
+	DefaultIncrementalRelayFeeRate = FeeRate(1)
+	// DogecoinIncrementalRelayFeeRate is the incremental relay fee of doginals commit
+	// replacements in koinu/kB, the 0.001 DOGE/kB minimum relay fee.
+	DogecoinIncrementalRelayFeeRate = FeeRate(100000)
+)
+
+var (
+	ErrNotReplacement         = errors.New("replacement does not spend the original commit tx inputs")
+	ErrReplacementFeeTooLow   = errors.New("replacement fee too low")
+	ErrReplacementNotSignaled = errors.New("original commit tx does not signal replaceability")
+)
+
+// ReplaceCommit builds a BIP-125 replacement of original.CommitTx paying commitFeeRate,
+// through the chain backend Inscribe uses for network. The reveal funding outputs are
+// rebuilt from the same request, so they keep their values and scripts and the extra
+// fee comes out of the change output. The reveal transactions spend the new commit
+// txid, so they are re-signed and returned as well.
+func ReplaceCommit(network *chaincfg.Params, request *InscriptionRequest, original *InscribeTxs, commitFeeRate FeeRate) (*InscribeTxs, error) {
+	originalTx, err := newTxFromHex(original.CommitTx)
+	if err != nil {
+		return nil, err
+	}
+
+	replacementRequest := *request
+	replacementRequest.CommitFeeRate = commitFeeRate
+	backend := ChainBackendFor(network)
+	replacement, err := backend.Inscribe(&replacementRequest)
+	if err != nil {
+		return nil, err
+	}
+	if replacement.CommitTx == "" {
+		return nil, ErrInsufficientBalance
+	}
+	replacementTx, err := newTxFromHex(replacement.CommitTx)
+	if err != nil {
+		return nil, err
+	}
+	prevOuts, err := commitPrevOutValues(request.CommitTxPrevOutputList)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkReplacement(backend, originalTx, replacementTx, prevOuts); err != nil {
+		return nil, err
+	}
+	return replacement, nil
+}
+
+// commitPrevOutValues returns the values of the commit tx inputs by outpoint.
+func commitPrevOutValues(prevOutputs []*PrevOutput) (map[wire.OutPoint]int64, error) {
+	values := make(map[wire.OutPoint]int64, len(prevOutputs))
+	for _, prevOutput := range prevOutputs {
+		txHash, err := chainhash.NewHashFromStr(prevOutput.TxId)
+		if err != nil {
+			return nil, err
+		}
+		values[*wire.NewOutPoint(txHash, prevOutput.VOut)] = prevOutput.Amount
+	}
+	return values, nil
+}
+
+// incrementalRelayFee returns the fee a replacement of tx must add for its own size,
+// in the fee unit of backend.
+func incrementalRelayFee(backend ChainBackend, tx *wire.MsgTx) int64 {
+	if _, ok := backend.(*DogecoinBackend); ok {
+		return dogecoinFee(DogecoinIncrementalRelayFeeRate, tx.SerializeSize())
+	}
+	return DefaultIncrementalRelayFeeRate.FeeForVSize(mempool.GetTxVirtualSize(btcutil.NewTx(tx)))
+}
+
+func checkReplacement(backend ChainBackend, originalTx, replacementTx *wire.MsgTx, prevOuts map[wire.OutPoint]int64) error {
+	// BIP-125 rule 1: the original must signal replaceability
+	signaled := false
+	for _, in := range originalTx.TxIn {
+		if in.Sequence <= mempool.MaxRBFSequence {
+			signaled = true
+			break
+		}
+	}
+	if !signaled {
+		return ErrReplacementNotSignaled
+	}
+
+	if len(originalTx.TxIn) != len(replacementTx.TxIn) {
+		return ErrNotReplacement
+	}
+	inputValue := int64(0)
+	for i, in := range originalTx.TxIn {
+		if in.PreviousOutPoint != replacementTx.TxIn[i].PreviousOutPoint {
+			return ErrNotReplacement
+		}
+		inputValue += prevOuts[in.PreviousOutPoint]
+	}
+	originalFee, fee := inputValue, inputValue
+	for _, out := range originalTx.TxOut {
+		originalFee -= out.Value
+	}
+	for _, out := range replacementTx.TxOut {
+		fee -= out.Value
+	}
+	originalVSize := mempool.GetTxVirtualSize(btcutil.NewTx(originalTx))
+	vsize := mempool.GetTxVirtualSize(btcutil.NewTx(replacementTx))
+
+	// BIP-125 rules 3 and 4: pay more in absolute terms, and at least the
+	// incremental relay fee for the replacement's own size on top of that
+	minFee := originalFee + incrementalRelayFee(backend, replacementTx)
+	if fee < minFee {
+		return fmt.Errorf("%w: fee %d, need at least %d (original fee %d)", ErrReplacementFeeTooLow, fee, minFee, originalFee)
+	}
+	// BIP-125 rule 6: the replacement fee rate must be higher than the original
+	if fee*originalVSize <= originalFee*vsize {
+		return fmt.Errorf("%w: fee rate %d/%d not higher than original %d/%d", ErrReplacementFeeTooLow, fee, vsize, originalFee, originalVSize)
+	}
+	return nil
+}
+
+func newTxFromHex(txHex string) (*wire.MsgTx, error) {
+	txBytes, err := hex.DecodeString(txHex)
+	if err != nil {
+		return nil, err
+	}
+	tx := wire.NewMsgTx(DefaultTxVersion)
+	if err := tx.Deserialize(bytes.NewReader(txBytes)); err != nil {
+		return nil, err
+	}
+	return tx, nil
+}
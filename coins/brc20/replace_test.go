@@ -0,0 +1,98 @@
+package brc20
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/chaincfg"
+)
+
+func TestReplaceCommit(t *testing.T) {
+	network := &chaincfg.TestNet3Params
+	request := newTestInscriptionRequest()
+
+	original, err := Inscribe(network, request)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	replacement, err := ReplaceCommit(network, request, original, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if replacement.CommitTxFee <= original.CommitTxFee {
+		t.Fatalf("replacement fee %d not higher than original %d", replacement.CommitTxFee, original.CommitTxFee)
+	}
+
+	originalTx, _ := newTxFromHex(original.CommitTx)
+	replacementTx, _ := newTxFromHex(replacement.CommitTx)
+	for i := range request.InscriptionDataList {
+		if originalTx.TxOut[i].Value != replacementTx.TxOut[i].Value {
+			t.Fatalf("reveal output %d changed: %d != %d", i, originalTx.TxOut[i].Value, replacementTx.TxOut[i].Value)
+		}
+	}
+	for i, revealTxHex := range replacement.RevealTxs {
+		revealTx, _ := newTxFromHex(revealTxHex)
+		if revealTx.TxIn[0].PreviousOutPoint.Hash != replacementTx.TxHash() {
+			t.Fatalf("reveal %d does not spend the replacement commit tx", i)
+		}
+	}
+	t.Log(replacement.CommitTx)
+
+	if _, err = ReplaceCommit(network, request, original, 2); !errors.Is(err, ErrReplacementFeeTooLow) {
+		t.Fatalf("expected ErrReplacementFeeTooLow, got %v", err)
+	}
+}
+
+func TestReplaceCommitDogecoin(t *testing.T) {
+	network := DogecoinTestNetParams
+	wif, _ := btcutil.DecodeWIF("cPnvkvUYyHcSSS26iD1dkrJdV7k1RoUqJLhn3CYxpo398PdLVE22")
+	address, _ := btcutil.NewAddressPubKeyHash(btcutil.Hash160(wif.PrivKey.PubKey().SerializeCompressed()), network)
+	request := &InscriptionRequest{
+		CommitTxPrevOutputList: []*PrevOutput{{
+			TxId:       "fcd1a1c33df653427e20159a799e6c1ba28421fd168fe353a54508c956fb382e",
+			VOut:       0,
+			Amount:     500000000,
+			Address:    address.EncodeAddress(),
+			PrivateKey: "cPnvkvUYyHcSSS26iD1dkrJdV7k1RoUqJLhn3CYxpo398PdLVE22",
+		}},
+		CommitFeeRate: 1000000,
+		RevealFeeRate: 1000000,
+		InscriptionDataList: []InscriptionData{{
+			ContentType: "text/plain;charset=utf-8",
+			Body:        []byte(`{"p":"drc-20","op":"mint","tick":"dogi","amt":"1000"}`),
+			RevealAddr:  address.EncodeAddress(),
+		}},
+		ChangeAddress: address.EncodeAddress(),
+	}
+	original, err := Inscribe(network, request)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the replacement is built by the doginals backend, with p2sh reveal chains
+	replacement, err := ReplaceCommit(network, request, original, 2000000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if replacement.CommitTxFee <= original.CommitTxFee || len(replacement.RevealTxChainLengths) != 1 {
+		t.Fatalf("unexpected replacement %+v", replacement)
+	}
+	replacementTx, _ := newTxFromHex(replacement.CommitTx)
+	revealTx, _ := newTxFromHex(replacement.RevealTxs[0])
+	if revealTx.TxIn[0].PreviousOutPoint.Hash != replacementTx.TxHash() || len(revealTx.TxIn[0].SignatureScript) == 0 {
+		t.Fatal("the reveal must spend the replacement commit tx from a scriptSig")
+	}
+
+	// the fee rates are in koinu/kB, so the incremental relay fee is too
+	if _, err = ReplaceCommit(network, request, original, 1000001); !errors.Is(err, ErrReplacementFeeTooLow) {
+		t.Fatalf("expected ErrReplacementFeeTooLow, got %v", err)
+	}
+
+	// the replacement outputs are checked for dust like Inscribe's
+	request.CommitOutputs = []*TxOutput{{Address: address.EncodeAddress(), Amount: DogecoinDustLimit - 1}}
+	if _, err = ReplaceCommit(network, request, original, 2000000); !errors.Is(err, ErrDustOutput) {
+		t.Fatalf("expected ErrDustOutput, got %v", err)
+	}
+}
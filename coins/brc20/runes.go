@@ -0,0 +1,646 @@
+package brc20
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+)
+
+// runestone tags, flags and limits as defined by ord
+const (
+	runeTagBody         = 0
+	runeTagFlags        = 2
+	runeTagRune         = 4
+	runeTagPremine      = 6
+	runeTagCap          = 8
+	runeTagAmount       = 10
+	runeTagHeightStart  = 12
+	runeTagHeightEnd    = 14
+	runeTagOffsetStart  = 16
+	runeTagOffsetEnd    = 18
+	runeTagMint         = 20
+	runeTagPointer      = 22
+	runeTagCenotaph     = 126
+	runeTagDivisibility = 1
+	runeTagSpacers      = 3
+	runeTagSymbol       = 5
+	runeTagNop          = 127
+
+	runeFlagEtching  = 0
+	runeFlagTerms    = 1
+	runeFlagTurbo    = 2
+	runeFlagCenotaph = 127
+
+	// InscriptionTagRune is the envelope tag carrying the rune commitment of an etching.
+	InscriptionTagRune = 13
+
+	RuneMaxDivisibility = 38
+	RuneMaxSpacers      = 0b00000111_11111111_11111111_11111111
+	runeMaxVarintSize   = 19
+)
+
+// cenotaph flaws
+const (
+	RuneFlawEdictOutput         = "edict output greater than transaction output count"
+	RuneFlawEdictRuneId         = "invalid rune ID in edict"
+	RuneFlawInvalidScript       = "invalid script in OP_RETURN"
+	RuneFlawOpcode              = "non-pushdata opcode in OP_RETURN"
+	RuneFlawSupplyOverflow      = "supply overflows u128"
+	RuneFlawTrailingIntegers    = "trailing integers in body"
+	RuneFlawTruncatedField      = "field with missing value"
+	RuneFlawUnrecognizedEvenTag = "unrecognized even tag"
+	RuneFlawUnrecognizedFlag    = "unrecognized field"
+	RuneFlawVarint              = "invalid varint"
+)
+
+var (
+	ErrInvalidRune     = errors.New("invalid rune")
+	ErrInvalidRuneId   = errors.New("invalid rune id")
+	ErrVarintOverflow  = errors.New("varint overflows u128")
+	ErrVarintTruncated = errors.New("truncated varint")
+
+	maxUint128 = new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 128), big.NewInt(1))
+)
+
+type RuneId struct {
+	Block uint64
+	Tx    uint32
+}
+
+func (id RuneId) String() string {
+	return fmt.Sprintf("%d:%d", id.Block, id.Tx)
+}
+
+func ParseRuneId(s string) (RuneId, error) {
+	parts := strings.Split(s, ":")
+	if len(parts) != 2 {
+		return RuneId{}, fmt.Errorf("%w: %s", ErrInvalidRuneId, s)
+	}
+	block, err := strconv.ParseUint(parts[0], 10, 64)
+	if err != nil {
+		return RuneId{}, fmt.Errorf("%w: %s", ErrInvalidRuneId, s)
+	}
+	tx, err := strconv.ParseUint(parts[1], 10, 32)
+	if err != nil {
+		return RuneId{}, fmt.Errorf("%w: %s", ErrInvalidRuneId, s)
+	}
+	return RuneId{Block: block, Tx: uint32(tx)}, nil
+}
+
+func (id RuneId) less(other RuneId) bool {
+	return id.Block < other.Block || (id.Block == other.Block && id.Tx < other.Tx)
+}
+
+// Rune is a rune name, a base-26 u128.
+type Rune struct {
+	Value *big.Int
+}
+
+func ParseRune(name string) (Rune, error) {
+	value := new(big.Int)
+	for i, c := range name {
+		if i > 0 {
+			value.Add(value, big.NewInt(1))
+		}
+		value.Mul(value, big.NewInt(26))
+		if c < 'A' || c > 'Z' {
+			return Rune{}, fmt.Errorf("%w: %s", ErrInvalidRune, name)
+		}
+		value.Add(value, big.NewInt(int64(c-'A')))
+		if value.Cmp(maxUint128) > 0 {
+			return Rune{}, fmt.Errorf("%w: %s", ErrInvalidRune, name)
+		}
+	}
+	if name == "" {
+		return Rune{}, fmt.Errorf("%w: empty name", ErrInvalidRune)
+	}
+	return Rune{Value: value}, nil
+}
+
+// ParseSpacedRune parses a rune name with "•" or "." spacers, e.g. "UNCOMMON•GOODS".
+func ParseSpacedRune(name string) (Rune, uint32, error) {
+	var letters strings.Builder
+	spacers := uint32(0)
+	for _, c := range name {
+		if c == '•' || c == '.' {
+			count := letters.Len()
+			if count == 0 || count > 31 || spacers&(1<<(count-1)) != 0 {
+				return Rune{}, 0, fmt.Errorf("%w: %s", ErrInvalidRune, name)
+			}
+			spacers |= 1 << (count - 1)
+			continue
+		}
+		letters.WriteRune(c)
+	}
+	if spacers != 0 && spacers >= 1<<(letters.Len()-1) {
+		return Rune{}, 0, fmt.Errorf("%w: trailing spacer in %s", ErrInvalidRune, name)
+	}
+	r, err := ParseRune(letters.String())
+	return r, spacers, err
+}
+
+func (r Rune) String() string {
+	n := new(big.Int).Add(r.Value, big.NewInt(1))
+	var symbol []byte
+	twentySix := big.NewInt(26)
+	mod := new(big.Int)
+	for n.Sign() > 0 {
+		n.Sub(n, big.NewInt(1))
+		n.DivMod(n, twentySix, mod)
+		symbol = append(symbol, byte('A'+mod.Int64()))
+	}
+	for i, j := 0, len(symbol)-1; i < j; i, j = i+1, j-1 {
+		symbol[i], symbol[j] = symbol[j], symbol[i]
+	}
+	return string(symbol)
+}
+
+// Commitment returns the bytes an etching must push in a tapscript of its reveal
+// input: the little-endian rune value without trailing zero bytes.
+func (r Rune) Commitment() []byte {
+	bytes := r.Value.Bytes()
+	commitment := make([]byte, len(bytes))
+	for i := range bytes {
+		commitment[i] = bytes[len(bytes)-1-i]
+	}
+	return commitment
+}
+
+type Edict struct {
+	Id     RuneId
+	Amount *big.Int
+	Output uint32
+}
+
+type Terms struct {
+	Amount      *big.Int
+	Cap         *big.Int
+	HeightStart *uint64
+	HeightEnd   *uint64
+	OffsetStart *uint64
+	OffsetEnd   *uint64
+}
+
+type Etching struct {
+	Divisibility *uint8
+	Premine      *big.Int
+	Rune         *Rune
+	Spacers      *uint32
+	Symbol       *rune
+	Terms        *Terms
+	Turbo        bool
+}
+
+type Runestone struct {
+	Edicts  []Edict
+	Etching *Etching
+	Mint    *RuneId
+	Pointer *uint32
+}
+
+// Cenotaph is a malformed runestone. Its etching and mint are still recorded, but
+// all runes input to the transaction are burned.
+type Cenotaph struct {
+	Etching *Rune
+	Flaw    string
+	Mint    *RuneId
+}
+
+// Encipher returns the OP_RETURN OP_13 output script of the runestone.
+func (runestone *Runestone) Encipher() ([]byte, error) {
+	var payload []byte
+	var err error
+	addTag := func(tag uint64, values ...*big.Int) {
+		for _, value := range values {
+			if err != nil {
+				return
+			}
+			payload = appendVarint(payload, new(big.Int).SetUint64(tag))
+			payload, err = appendVarintChecked(payload, value)
+		}
+	}
+
+	if etching := runestone.Etching; etching != nil {
+		flags := new(big.Int).SetBit(new(big.Int), runeFlagEtching, 1)
+		if etching.Terms != nil {
+			flags.SetBit(flags, runeFlagTerms, 1)
+		}
+		if etching.Turbo {
+			flags.SetBit(flags, runeFlagTurbo, 1)
+		}
+		addTag(runeTagFlags, flags)
+		if etching.Rune != nil {
+			addTag(runeTagRune, etching.Rune.Value)
+		}
+		if etching.Divisibility != nil {
+			addTag(runeTagDivisibility, big.NewInt(int64(*etching.Divisibility)))
+		}
+		if etching.Spacers != nil {
+			addTag(runeTagSpacers, big.NewInt(int64(*etching.Spacers)))
+		}
+		if etching.Symbol != nil {
+			addTag(runeTagSymbol, big.NewInt(int64(*etching.Symbol)))
+		}
+		if etching.Premine != nil {
+			addTag(runeTagPremine, etching.Premine)
+		}
+		if terms := etching.Terms; terms != nil {
+			if terms.Amount != nil {
+				addTag(runeTagAmount, terms.Amount)
+			}
+			if terms.Cap != nil {
+				addTag(runeTagCap, terms.Cap)
+			}
+			for _, field := range []struct {
+				tag   uint64
+				value *uint64
+			}{
+				{runeTagHeightStart, terms.HeightStart},
+				{runeTagHeightEnd, terms.HeightEnd},
+				{runeTagOffsetStart, terms.OffsetStart},
+				{runeTagOffsetEnd, terms.OffsetEnd},
+			} {
+				if field.value != nil {
+					addTag(field.tag, new(big.Int).SetUint64(*field.value))
+				}
+			}
+		}
+	}
+	if runestone.Mint != nil {
+		addTag(runeTagMint, new(big.Int).SetUint64(runestone.Mint.Block), big.NewInt(int64(runestone.Mint.Tx)))
+	}
+	if runestone.Pointer != nil {
+		addTag(runeTagPointer, big.NewInt(int64(*runestone.Pointer)))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if len(runestone.Edicts) > 0 {
+		payload = appendVarint(payload, big.NewInt(runeTagBody))
+		edicts := append([]Edict{}, runestone.Edicts...)
+		sort.SliceStable(edicts, func(i, j int) bool {
+			return edicts[i].Id.less(edicts[j].Id)
+		})
+		previous := RuneId{}
+		for _, edict := range edicts {
+			block := edict.Id.Block - previous.Block
+			tx := uint64(edict.Id.Tx)
+			if block == 0 {
+				tx = uint64(edict.Id.Tx - previous.Tx)
+			}
+			payload = appendVarint(payload, new(big.Int).SetUint64(block))
+			payload = appendVarint(payload, new(big.Int).SetUint64(tx))
+			if payload, err = appendVarintChecked(payload, edict.Amount); err != nil {
+				return nil, err
+			}
+			payload = appendVarint(payload, big.NewInt(int64(edict.Output)))
+			previous = edict.Id
+		}
+	}
+
+	builder := txscript.NewScriptBuilder().
+		AddOp(txscript.OP_RETURN).
+		AddOp(txscript.OP_13)
+	for i := 0; i < len(payload); i += txscript.MaxScriptElementSize {
+		end := i + txscript.MaxScriptElementSize
+		if end > len(payload) {
+			end = len(payload)
+		}
+		builder.AddFullData(payload[i:end])
+	}
+	return builder.Script()
+}
+
+// TxOutput returns the runestone as a zero value output for Transfer.
+func (runestone *Runestone) TxOutput() (*TxOutput, error) {
+	script, err := runestone.Encipher()
+	if err != nil {
+		return nil, err
+	}
+	return &TxOutput{PkScript: script}, nil
+}
+
+// DecipherRunestone decodes the runestone of tx. It returns nil, nil if tx has no
+// runestone output, and a cenotaph if the runestone is malformed.
+func DecipherRunestone(tx *wire.MsgTx) (*Runestone, *Cenotaph) {
+	payload, flaw, found := runestonePayload(tx)
+	if !found {
+		return nil, nil
+	}
+	if flaw != "" {
+		return nil, &Cenotaph{Flaw: flaw}
+	}
+
+	var integers []*big.Int
+	for len(payload) > 0 {
+		value, size, err := decodeVarint(payload)
+		if err != nil {
+			return nil, &Cenotaph{Flaw: RuneFlawVarint}
+		}
+		integers = append(integers, value)
+		payload = payload[size:]
+	}
+
+	fields := make(map[uint64][]*big.Int)
+	var evenTags []uint64
+	var edicts []Edict
+	for i := 0; i < len(integers); i += 2 {
+		tag := integers[i]
+		if tag.Sign() == runeTagBody {
+			id := RuneId{}
+			for j := i + 1; j < len(integers); j += 4 {
+				if len(integers)-j < 4 {
+					flaw = RuneFlawTrailingIntegers
+					break
+				}
+				next, ok := nextRuneId(id, integers[j], integers[j+1])
+				if !ok {
+					flaw = RuneFlawEdictRuneId
+					break
+				}
+				output := integers[j+3]
+				if !output.IsUint64() || output.Uint64() > uint64(len(tx.TxOut)) {
+					flaw = RuneFlawEdictOutput
+					break
+				}
+				id = next
+				edicts = append(edicts, Edict{Id: next, Amount: integers[j+2], Output: uint32(output.Uint64())})
+			}
+			break
+		}
+		if i+1 >= len(integers) {
+			flaw = RuneFlawTruncatedField
+			break
+		}
+		if !tag.IsUint64() {
+			// an unrecognized tag that is even makes a cenotaph, odd ones are ignored
+			if tag.Bit(0) == 0 {
+				flaw = RuneFlawUnrecognizedEvenTag
+			}
+			continue
+		}
+		if _, ok := fields[tag.Uint64()]; !ok && tag.Bit(0) == 0 {
+			evenTags = append(evenTags, tag.Uint64())
+		}
+		fields[tag.Uint64()] = append(fields[tag.Uint64()], integers[i+1])
+	}
+
+	flags := new(big.Int)
+	takeField(fields, runeTagFlags, 1, func(values []*big.Int) bool {
+		flags.Set(values[0])
+		return true
+	})
+
+	var etching *Etching
+	if takeFlag(flags, runeFlagEtching) {
+		etching = &Etching{}
+		takeField(fields, runeTagDivisibility, 1, func(values []*big.Int) bool {
+			if !values[0].IsUint64() || values[0].Uint64() > RuneMaxDivisibility {
+				return false
+			}
+			divisibility := uint8(values[0].Uint64())
+			etching.Divisibility = &divisibility
+			return true
+		})
+		takeField(fields, runeTagPremine, 1, func(values []*big.Int) bool {
+			etching.Premine = values[0]
+			return true
+		})
+		takeField(fields, runeTagRune, 1, func(values []*big.Int) bool {
+			etching.Rune = &Rune{Value: values[0]}
+			return true
+		})
+		takeField(fields, runeTagSpacers, 1, func(values []*big.Int) bool {
+			if !values[0].IsUint64() || values[0].Uint64() > RuneMaxSpacers {
+				return false
+			}
+			spacers := uint32(values[0].Uint64())
+			etching.Spacers = &spacers
+			return true
+		})
+		takeField(fields, runeTagSymbol, 1, func(values []*big.Int) bool {
+			if !values[0].IsUint64() || values[0].Uint64() > math.MaxUint32 || !utf8.ValidRune(rune(values[0].Uint64())) {
+				return false
+			}
+			symbol := rune(values[0].Uint64())
+			etching.Symbol = &symbol
+			return true
+		})
+		if takeFlag(flags, runeFlagTerms) {
+			terms := &Terms{}
+			takeField(fields, runeTagCap, 1, func(values []*big.Int) bool {
+				terms.Cap = values[0]
+				return true
+			})
+			takeField(fields, runeTagAmount, 1, func(values []*big.Int) bool {
+				terms.Amount = values[0]
+				return true
+			})
+			for _, field := range []struct {
+				tag   uint64
+				value **uint64
+			}{
+				{runeTagHeightStart, &terms.HeightStart},
+				{runeTagHeightEnd, &terms.HeightEnd},
+				{runeTagOffsetStart, &terms.OffsetStart},
+				{runeTagOffsetEnd, &terms.OffsetEnd},
+			} {
+				field := field
+				takeField(fields, field.tag, 1, func(values []*big.Int) bool {
+					if !values[0].IsUint64() {
+						return false
+					}
+					value := values[0].Uint64()
+					*field.value = &value
+					return true
+				})
+			}
+			etching.Terms = terms
+		}
+		etching.Turbo = takeFlag(flags, runeFlagTurbo)
+	}
+
+	var mint *RuneId
+	takeField(fields, runeTagMint, 2, func(values []*big.Int) bool {
+		if !values[0].IsUint64() || !values[1].IsUint64() || values[1].Uint64() > math.MaxUint32 {
+			return false
+		}
+		// a rune id in block 0 only names a rune in tx 0
+		if values[0].Sign() == 0 && values[1].Sign() != 0 {
+			return false
+		}
+		mint = &RuneId{Block: values[0].Uint64(), Tx: uint32(values[1].Uint64())}
+		return true
+	})
+
+	var pointer *uint32
+	takeField(fields, runeTagPointer, 1, func(values []*big.Int) bool {
+		if !values[0].IsUint64() || values[0].Uint64() >= uint64(len(tx.TxOut)) {
+			return false
+		}
+		value := uint32(values[0].Uint64())
+		pointer = &value
+		return true
+	})
+
+	if flaw == "" && etching != nil && etching.supply() == nil {
+		flaw = RuneFlawSupplyOverflow
+	}
+	if flaw == "" && flags.Sign() != 0 {
+		flaw = RuneFlawUnrecognizedFlag
+	}
+	if flaw == "" {
+		for _, tag := range evenTags {
+			if _, ok := fields[tag]; ok {
+				flaw = RuneFlawUnrecognizedEvenTag
+				break
+			}
+		}
+	}
+	if flaw != "" {
+		cenotaph := &Cenotaph{Flaw: flaw, Mint: mint}
+		if etching != nil {
+			cenotaph.Etching = etching.Rune
+		}
+		return nil, cenotaph
+	}
+	return &Runestone{
+		Edicts:  edicts,
+		Etching: etching,
+		Mint:    mint,
+		Pointer: pointer,
+	}, nil
+}
+
+// supply returns premine + cap * amount, or nil if it overflows u128.
+func (etching *Etching) supply() *big.Int {
+	supply := new(big.Int)
+	if etching.Premine != nil {
+		supply.Set(etching.Premine)
+	}
+	if etching.Terms != nil && etching.Terms.Cap != nil && etching.Terms.Amount != nil {
+		minted := new(big.Int).Mul(etching.Terms.Cap, etching.Terms.Amount)
+		if minted.Cmp(maxUint128) > 0 {
+			return nil
+		}
+		supply.Add(supply, minted)
+	}
+	if supply.Cmp(maxUint128) > 0 {
+		return nil
+	}
+	return supply
+}
+
+func runestonePayload(tx *wire.MsgTx) ([]byte, string, bool) {
+	for _, out := range tx.TxOut {
+		tokenizer := txscript.MakeScriptTokenizer(0, out.PkScript)
+		if !tokenizer.Next() || tokenizer.Opcode() != txscript.OP_RETURN {
+			continue
+		}
+		if !tokenizer.Next() || tokenizer.Opcode() != txscript.OP_13 {
+			continue
+		}
+		var payload []byte
+		for tokenizer.Next() {
+			if tokenizer.Opcode() > txscript.OP_PUSHDATA4 {
+				return nil, RuneFlawOpcode, true
+			}
+			payload = append(payload, tokenizer.Data()...)
+		}
+		if tokenizer.Err() != nil {
+			return nil, RuneFlawInvalidScript, true
+		}
+		return payload, "", true
+	}
+	return nil, "", false
+}
+
+func nextRuneId(id RuneId, block, tx *big.Int) (RuneId, bool) {
+	if !block.IsUint64() || !tx.IsUint64() {
+		return RuneId{}, false
+	}
+	if block.Uint64() > math.MaxUint64-id.Block {
+		return RuneId{}, false
+	}
+	next := RuneId{Block: id.Block + block.Uint64()}
+	txValue := tx.Uint64()
+	if block.Sign() == 0 {
+		txValue += uint64(id.Tx)
+	}
+	if txValue > math.MaxUint32 {
+		return RuneId{}, false
+	}
+	next.Tx = uint32(txValue)
+	// as with a mint, a rune id in block 0 only names a rune in tx 0
+	if next.Block == 0 && next.Tx > 0 {
+		return RuneId{}, false
+	}
+	return next, true
+}
+
+// takeField consumes count values of tag if parse accepts them. Rejected values stay
+// in fields, so that an even tag with an invalid value makes a cenotaph.
+func takeField(fields map[uint64][]*big.Int, tag uint64, count int, parse func(values []*big.Int) bool) {
+	values := fields[tag]
+	if len(values) < count || !parse(values[:count]) {
+		return
+	}
+	if len(values) == count {
+		delete(fields, tag)
+	} else {
+		fields[tag] = values[count:]
+	}
+}
+
+func takeFlag(flags *big.Int, flag int) bool {
+	if flags.Bit(flag) == 0 {
+		return false
+	}
+	flags.SetBit(flags, flag, 0)
+	return true
+}
+
+func appendVarint(buf []byte, value *big.Int) []byte {
+	v := new(big.Int).Set(value)
+	b := new(big.Int)
+	for v.BitLen() > 7 {
+		b.And(v, big.NewInt(0x7f))
+		buf = append(buf, byte(b.Int64())|0x80)
+		v.Rsh(v, 7)
+	}
+	return append(buf, byte(v.Int64()))
+}
+
+func appendVarintChecked(buf []byte, value *big.Int) ([]byte, error) {
+	if value == nil || value.Sign() < 0 || value.Cmp(maxUint128) > 0 {
+		return nil, ErrVarintOverflow
+	}
+	return appendVarint(buf, value), nil
+}
+
+func decodeVarint(buf []byte) (*big.Int, int, error) {
+	value := new(big.Int)
+	for i := 0; i < len(buf) && i < runeMaxVarintSize; i++ {
+		b := buf[i]
+		if i == runeMaxVarintSize-1 && b&0b0111_1100 != 0 {
+			return nil, 0, ErrVarintOverflow
+		}
+		value.Or(value, new(big.Int).Lsh(big.NewInt(int64(b&0x7f)), uint(7*i)))
+		if b&0x80 == 0 {
+			return value, i + 1, nil
+		}
+	}
+	if len(buf) >= runeMaxVarintSize {
+		return nil, 0, ErrVarintOverflow
+	}
+	return nil, 0, ErrVarintTruncated
+}
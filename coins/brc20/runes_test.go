@@ -0,0 +1,256 @@
+package brc20
+
+import (
+	"bytes"
+	"encoding/hex"
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+)
+
+func TestRuneName(t *testing.T) {
+	for name, value := range map[string]int64{"A": 0, "B": 1, "Z": 25, "AA": 26, "AZ": 51, "BA": 52, "ZZ": 701, "AAA": 702} {
+		r, err := ParseRune(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if r.Value.Int64() != value {
+			t.Fatalf("%s: got %s, want %d", name, r.Value, value)
+		}
+		if r.String() != name {
+			t.Fatalf("%d: got %s, want %s", value, r.String(), name)
+		}
+	}
+	if r := (Rune{Value: maxUint128}); r.String() != "BCGDENLQRQWDSLRUGSNLBTMFIJAV" {
+		t.Fatal(r.String())
+	}
+	for _, name := range []string{"", "a", "BCGDENLQRQWDSLRUGSNLBTMFIJAW"} {
+		if _, err := ParseRune(name); err == nil {
+			t.Fatalf("%q: expected error", name)
+		}
+	}
+
+	r, spacers, err := ParseSpacedRune("UNCOMMON•GOODS")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.String() != "UNCOMMONGOODS" || spacers != 1<<7 {
+		t.Fatal(r.String(), spacers)
+	}
+	for _, name := range []string{"•A", "A•", "A••B"} {
+		if _, _, err := ParseSpacedRune(name); err == nil {
+			t.Fatalf("%q: expected error", name)
+		}
+	}
+
+	if commitment := (Rune{Value: big.NewInt(0x0102)}).Commitment(); !bytes.Equal(commitment, []byte{0x02, 0x01}) {
+		t.Fatal(hex.EncodeToString(commitment))
+	}
+}
+
+func TestVarint(t *testing.T) {
+	for _, c := range []struct {
+		value   *big.Int
+		encoded string
+	}{
+		{big.NewInt(0), "00"},
+		{big.NewInt(127), "7f"},
+		{big.NewInt(128), "8001"},
+		{big.NewInt(300), "ac02"},
+		{maxUint128, "ffffffffffffffffffffffffffffffffffff03"},
+	} {
+		encoded := appendVarint(nil, c.value)
+		if hex.EncodeToString(encoded) != c.encoded {
+			t.Fatalf("%s: got %x, want %s", c.value, encoded, c.encoded)
+		}
+		value, size, err := decodeVarint(encoded)
+		if err != nil || size != len(encoded) || value.Cmp(c.value) != 0 {
+			t.Fatal(c.value, value, size, err)
+		}
+	}
+	overflow, _ := hex.DecodeString("ffffffffffffffffffffffffffffffffffff04")
+	if _, _, err := decodeVarint(overflow); err != ErrVarintOverflow {
+		t.Fatal(err)
+	}
+	if _, _, err := decodeVarint([]byte{0x80}); err != ErrVarintTruncated {
+		t.Fatal(err)
+	}
+	if _, err := appendVarintChecked(nil, new(big.Int).Add(maxUint128, big.NewInt(1))); err != ErrVarintOverflow {
+		t.Fatal(err)
+	}
+}
+
+func TestRunestoneRoundTrip(t *testing.T) {
+	r, spacers, _ := ParseSpacedRune("UNCOMMON•GOODS")
+	divisibility := uint8(2)
+	symbol := '⧉'
+	heightStart, offsetEnd := uint64(840000), uint64(1050000)
+	pointer := uint32(1)
+	runestone := &Runestone{
+		Edicts: []Edict{
+			{Id: RuneId{Block: 840000, Tx: 3}, Amount: big.NewInt(500), Output: 2},
+			{Id: RuneId{Block: 1, Tx: 7}, Amount: maxUint128, Output: 0},
+			{Id: RuneId{Block: 840000, Tx: 1}, Amount: big.NewInt(0), Output: 1},
+		},
+		Etching: &Etching{
+			Divisibility: &divisibility,
+			Premine:      big.NewInt(1000),
+			Rune:         &r,
+			Spacers:      &spacers,
+			Symbol:       &symbol,
+			Terms: &Terms{
+				Amount:      big.NewInt(1),
+				Cap:         big.NewInt(1111111),
+				HeightStart: &heightStart,
+				OffsetEnd:   &offsetEnd,
+			},
+			Turbo: true,
+		},
+		Mint:    &RuneId{Block: 1, Tx: 0},
+		Pointer: &pointer,
+	}
+	script, err := runestone.Encipher()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if script[0] != txscript.OP_RETURN || script[1] != txscript.OP_13 {
+		t.Fatal(hex.EncodeToString(script))
+	}
+
+	tx := wire.NewMsgTx(2)
+	tx.AddTxOut(wire.NewTxOut(546, []byte{txscript.OP_1}))
+	tx.AddTxOut(wire.NewTxOut(546, []byte{txscript.OP_1}))
+	tx.AddTxOut(wire.NewTxOut(0, script))
+	decoded, cenotaph := DecipherRunestone(tx)
+	if cenotaph != nil {
+		t.Fatal(cenotaph.Flaw)
+	}
+	// edicts are decoded in rune id order
+	runestone.Edicts = []Edict{runestone.Edicts[1], runestone.Edicts[2], runestone.Edicts[0]}
+	if !reflect.DeepEqual(runestone, decoded) {
+		t.Fatalf("got %+v, want %+v", decoded, runestone)
+	}
+
+	tx.TxOut = tx.TxOut[2:]
+	if runestone, cenotaph := DecipherRunestone(tx); runestone != nil || cenotaph == nil || cenotaph.Flaw != RuneFlawEdictOutput {
+		t.Fatal(runestone, cenotaph)
+	}
+
+	tx.TxOut = []*wire.TxOut{wire.NewTxOut(0, []byte{txscript.OP_RETURN})}
+	if runestone, cenotaph := DecipherRunestone(tx); runestone != nil || cenotaph != nil {
+		t.Fatal(runestone, cenotaph)
+	}
+}
+
+func TestRunestoneCenotaph(t *testing.T) {
+	payload := func(integers ...int64) []byte {
+		var buf []byte
+		for _, i := range integers {
+			buf = appendVarint(buf, big.NewInt(i))
+		}
+		// a raw push, the script builder would turn a single byte into OP_1..OP_16
+		return append([]byte{txscript.OP_RETURN, txscript.OP_13, byte(len(buf))}, buf...)
+	}
+	for _, c := range []struct {
+		script []byte
+		flaw   string
+	}{
+		{payload(runeTagCenotaph, 0), RuneFlawUnrecognizedEvenTag},
+		{payload(runeTagNop, 5, runeTagFlags, 1), ""},
+		{append([]byte{txscript.OP_RETURN, txscript.OP_13, 20, runeTagFlags},
+			appendVarint(nil, new(big.Int).Lsh(big.NewInt(1), runeFlagCenotaph))...), RuneFlawUnrecognizedFlag},
+		{payload(runeTagFlags, 1<<3), RuneFlawUnrecognizedFlag},
+		{payload(runeTagFlags, 1, runeTagDivisibility, 39), ""},
+		{payload(runeTagFlags), RuneFlawTruncatedField},
+		{payload(runeTagBody, 1, 1, 1), RuneFlawTrailingIntegers},
+		{payload(runeTagBody, 0, 0, 1, 0), ""},
+		{payload(runeTagBody, 0, 1, 1, 0), RuneFlawEdictRuneId},
+		{payload(runeTagMint, 1), RuneFlawUnrecognizedEvenTag},
+		{payload(runeTagMint, 0, runeTagMint, 1), RuneFlawUnrecognizedEvenTag},
+		{payload(runeTagMint, 1, runeTagMint, 0), ""},
+		{payload(runeTagPointer, 1), RuneFlawUnrecognizedEvenTag},
+		{[]byte{txscript.OP_RETURN, txscript.OP_13, txscript.OP_DATA_1, 0x80}, RuneFlawVarint},
+		{[]byte{txscript.OP_RETURN, txscript.OP_13, txscript.OP_DATA_1, 0x00, txscript.OP_VERIFY}, RuneFlawOpcode},
+		{[]byte{txscript.OP_RETURN, txscript.OP_13, txscript.OP_DATA_2, 0x00}, RuneFlawInvalidScript},
+	} {
+		tx := wire.NewMsgTx(2)
+		tx.AddTxOut(wire.NewTxOut(0, c.script))
+		runestone, cenotaph := DecipherRunestone(tx)
+		if c.flaw == "" {
+			if runestone == nil || cenotaph != nil {
+				t.Fatalf("%x: got cenotaph %+v", c.script, cenotaph)
+			}
+			continue
+		}
+		if runestone != nil || cenotaph == nil || cenotaph.Flaw != c.flaw {
+			t.Fatalf("%x: got %+v, %+v, want flaw %s", c.script, runestone, cenotaph, c.flaw)
+		}
+	}
+
+	// the etched rune is still recorded on a cenotaph
+	script := payload(runeTagFlags, 1, runeTagRune, 26, runeTagCenotaph, 0)
+	tx := wire.NewMsgTx(2)
+	tx.AddTxOut(wire.NewTxOut(0, script))
+	if _, cenotaph := DecipherRunestone(tx); cenotaph == nil || cenotaph.Etching == nil || cenotaph.Etching.String() != "AA" {
+		t.Fatal(cenotaph)
+	}
+}
+
+func TestTransferRunestone(t *testing.T) {
+	network := &chaincfg.TestNet3Params
+
+	runestone := &Runestone{
+		Edicts: []Edict{{Id: RuneId{Block: 2585189, Tx: 204}, Amount: big.NewInt(100), Output: 1}},
+	}
+	runestoneOutput, err := runestone.TxOutput()
+	if err != nil {
+		t.Fatal(err)
+	}
+	inputs := []*TxInput{{
+		TxId:       "25b9d08a26c8d47795301dd47a861cff0459d14f27fbd41cffaca17d9aa20f87",
+		VOut:       uint32(0),
+		Amount:     int64(249352),
+		Address:    "tb1qtsq9c4fje6qsmheql8gajwtrrdrs38kdzeersc",
+		PrivateKey: "cPnvkvUYyHcSSS26iD1dkrJdV7k1RoUqJLhn3CYxpo398PdLVE22",
+	}}
+	outputs := []*TxOutput{runestoneOutput, {
+		Address: "tb1pklh8lqax5l7m2ycypptv2emc4gata2dy28svnwcp9u32wlkenvsspcvhsr",
+		Amount:  int64(546),
+	}}
+	res, err := TransferWithChange(inputs, outputs, "tb1qtsq9c4fje6qsmheql8gajwtrrdrs38kdzeersc", 2, network)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx, err := newTxFromHex(res.Tx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, cenotaph := DecipherRunestone(tx)
+	if cenotaph != nil || !reflect.DeepEqual(decoded.Edicts, runestone.Edicts) {
+		t.Fatal(decoded, cenotaph)
+	}
+}
+
+func TestInscribeRuneCommitment(t *testing.T) {
+	request := newTestInscriptionRequest()
+	request.InscriptionDataList[0].Rune = "UNCOMMON•GOODS"
+	request.InscriptionDataList = request.InscriptionDataList[:1]
+	tool, err := newInscriptionTool(&chaincfg.TestNet3Params, request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, _ := ParseRune("UNCOMMONGOODS")
+	tag := append([]byte{txscript.OP_DATA_1, InscriptionTagRune, byte(len(r.Commitment()))}, r.Commitment()...)
+	if !bytes.Contains(tool.InscriptionTxCtxDataList[0].InscriptionScript, tag) {
+		t.Fatalf("rune commitment missing from %x", tool.InscriptionTxCtxDataList[0].InscriptionScript)
+	}
+
+	request.InscriptionDataList[0].Rune = "uncommon"
+	if _, err := newInscriptionTool(&chaincfg.TestNet3Params, request); err == nil {
+		t.Fatal("expected invalid rune error")
+	}
+}
@@ -0,0 +1,128 @@
+package brc20
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/btcec/v2/schnorr"
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/txscript"
+)
+
+type ScriptType string
+
+const (
+	ScriptTypeUnknown    ScriptType = "unknown"
+	ScriptTypeP2PK       ScriptType = "p2pk"
+	ScriptTypeP2PKH      ScriptType = "p2pkh"
+	ScriptTypeP2SHP2WPKH ScriptType = "p2sh-p2wpkh"
+	ScriptTypeP2SH       ScriptType = "p2sh"
+	ScriptTypeP2WPKH     ScriptType = "p2wpkh"
+	ScriptTypeP2WSH      ScriptType = "p2wsh"
+	ScriptTypeP2TR       ScriptType = "p2tr"
+)
+
+var (
+	ErrUnsupportedScript = errors.New("unsupported script")
+	ErrKeyMismatch       = errors.New("key does not match script")
+)
+
+// ClassifyScript returns the type of pkScript. A p2sh output is p2sh-p2wpkh when
+// redeemScript is a p2wpkh program, p2sh otherwise.
+func ClassifyScript(pkScript, redeemScript []byte) ScriptType {
+	switch {
+	case txscript.IsPayToTaproot(pkScript):
+		return ScriptTypeP2TR
+	case txscript.IsPayToWitnessPubKeyHash(pkScript):
+		return ScriptTypeP2WPKH
+	case txscript.IsPayToWitnessScriptHash(pkScript):
+		return ScriptTypeP2WSH
+	case txscript.IsPayToPubKeyHash(pkScript):
+		return ScriptTypeP2PKH
+	case txscript.IsPayToScriptHash(pkScript):
+		if txscript.IsPayToWitnessPubKeyHash(redeemScript) {
+			return ScriptTypeP2SHP2WPKH
+		}
+		return ScriptTypeP2SH
+	case txscript.IsPayToPubKey(pkScript):
+		return ScriptTypeP2PK
+	default:
+		return ScriptTypeUnknown
+	}
+}
+
+// keyScriptType returns the type of pkScript, a single-key output that privKey
+// signs for. tapMerkleRoot is the script tree root of a p2tr output, nil for
+// BIP-86. A p2sh output must be the p2sh-p2wpkh of the key.
+func keyScriptType(pkScript []byte, privKey *btcec.PrivateKey, tapMerkleRoot []byte) (ScriptType, error) {
+	pubKey := privKey.PubKey()
+	pubKeyHash := btcutil.Hash160(pubKey.SerializeCompressed())
+	var redeemScript []byte
+	if txscript.IsPayToScriptHash(pkScript) {
+		var err error
+		if redeemScript, err = PayToWitnessPubKeyHashScript(pubKeyHash); err != nil {
+			return ScriptTypeUnknown, err
+		}
+	}
+	scriptType := ClassifyScript(pkScript, redeemScript)
+
+	var match bool
+	switch scriptType {
+	case ScriptTypeP2TR:
+		outputKey := txscript.ComputeTaprootOutputKey(pubKey, tapMerkleRoot)
+		match = bytes.Equal(pkScript[2:], schnorr.SerializePubKey(outputKey))
+	case ScriptTypeP2WPKH:
+		match = bytes.Equal(pkScript[2:], pubKeyHash)
+	case ScriptTypeP2PKH:
+		match = bytes.Equal(pkScript[3:23], pubKeyHash)
+	case ScriptTypeP2SHP2WPKH:
+		match = bytes.Equal(pkScript[2:22], btcutil.Hash160(redeemScript))
+		if !match {
+			return scriptType, fmt.Errorf("%w: %x is not the p2sh-p2wpkh of the key, "+
+				"other p2sh outputs need their redeem script", ErrUnsupportedScript, pkScript)
+		}
+	case ScriptTypeP2PK:
+		pushed, err := txscript.PushedData(pkScript)
+		if err != nil {
+			return scriptType, err
+		}
+		match = bytes.Equal(pushed[0], pubKey.SerializeCompressed()) || bytes.Equal(pushed[0], pubKey.SerializeUncompressed())
+	default:
+		return scriptType, fmt.Errorf("%w: %s output %x", ErrUnsupportedScript, scriptType, pkScript)
+	}
+	if !match {
+		return scriptType, fmt.Errorf("%w: %s output %x", ErrKeyMismatch, scriptType, pkScript)
+	}
+	return scriptType, nil
+}
+
+// PubKeyAddress returns the address of type scriptType that pubKey alone spends:
+// p2pkh, p2sh-p2wpkh, p2wpkh or BIP-86 p2tr.
+func PubKeyAddress(pubKey *btcec.PublicKey, scriptType ScriptType, network *chaincfg.Params) (string, error) {
+	pubKeyHash := btcutil.Hash160(pubKey.SerializeCompressed())
+	var address btcutil.Address
+	var err error
+	switch scriptType {
+	case ScriptTypeP2PKH:
+		address, err = btcutil.NewAddressPubKeyHash(pubKeyHash, network)
+	case ScriptTypeP2SHP2WPKH:
+		var program []byte
+		if program, err = PayToWitnessPubKeyHashScript(pubKeyHash); err != nil {
+			return "", err
+		}
+		address, err = btcutil.NewAddressScriptHash(program, network)
+	case ScriptTypeP2WPKH:
+		address, err = btcutil.NewAddressWitnessPubKeyHash(pubKeyHash, network)
+	case ScriptTypeP2TR:
+		address, err = btcutil.NewAddressTaproot(schnorr.SerializePubKey(txscript.ComputeTaprootKeyNoScript(pubKey)), network)
+	default:
+		return "", fmt.Errorf("%w: no %s address for a key", ErrUnsupportedScript, scriptType)
+	}
+	if err != nil {
+		return "", err
+	}
+	return address.EncodeAddress(), nil
+}
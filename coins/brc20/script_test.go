@@ -0,0 +1,127 @@
+package brc20
+
+import (
+	"encoding/hex"
+	"errors"
+	"testing"
+
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+)
+
+func TestClassifyScript(t *testing.T) {
+	network := &chaincfg.TestNet3Params
+	ms := newTestMultisig(t)
+	wif, _ := btcutil.DecodeWIF("cPnvkvUYyHcSSS26iD1dkrJdV7k1RoUqJLhn3CYxpo398PdLVE22")
+	p2wpkhProgram, _ := PayToWitnessPubKeyHashScript(btcutil.Hash160(wif.PrivKey.PubKey().SerializeCompressed()))
+	p2pk, _ := txscript.NewScriptBuilder().AddData(wif.PrivKey.PubKey().SerializeCompressed()).AddOp(txscript.OP_CHECKSIG).Script()
+
+	tests := []struct {
+		address      string
+		pkScript     []byte
+		redeemScript []byte
+		scriptType   ScriptType
+	}{
+		{address: "tb1pklh8lqax5l7m2ycypptv2emc4gata2dy28svnwcp9u32wlkenvsspcvhsr", scriptType: ScriptTypeP2TR},
+		{address: "tb1qtsq9c4fje6qsmheql8gajwtrrdrs38kdzeersc", scriptType: ScriptTypeP2WPKH},
+		{address: "mouQtmBWDS7JnT65Grj2tPzdSmGKJgRMhE", scriptType: ScriptTypeP2PKH},
+		{address: "2NF33rckfiQTiE5Guk5ufUdwms8PgmtnEdc", redeemScript: p2wpkhProgram, scriptType: ScriptTypeP2SHP2WPKH},
+		{address: ms.p2sh, redeemScript: ms.script, scriptType: ScriptTypeP2SH},
+		{address: ms.p2wsh, scriptType: ScriptTypeP2WSH},
+		{pkScript: p2pk, scriptType: ScriptTypeP2PK},
+		{pkScript: []byte{txscript.OP_TRUE}, scriptType: ScriptTypeUnknown},
+	}
+	for _, test := range tests {
+		pkScript := test.pkScript
+		if test.address != "" {
+			var err error
+			if pkScript, err = AddrToPkScript(test.address, network); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if scriptType := ClassifyScript(pkScript, test.redeemScript); scriptType != test.scriptType {
+			t.Errorf("%x: expected %s, got %s", pkScript, test.scriptType, scriptType)
+		}
+	}
+}
+
+func TestTransferScriptType(t *testing.T) {
+	network := &chaincfg.TestNet3Params
+	ms := newTestMultisig(t)
+	otherWif := ms.wifs[1]
+	outs := []*TxOutput{{Address: "tb1qtsq9c4fje6qsmheql8gajwtrrdrs38kdzeersc", Amount: 90000}}
+	newInput := func(address, privateKey string) *TxInput {
+		return &TxInput{
+			TxId:       "25b9d08a26c8d47795301dd47a861cff0459d14f27fbd41cffaca17d9aa20f87",
+			VOut:       uint32(0),
+			Amount:     int64(100000),
+			Address:    address,
+			PrivateKey: privateKey,
+		}
+	}
+
+	tests := []struct {
+		in  *TxInput
+		err error
+	}{
+		{in: newInput("tb1qtsq9c4fje6qsmheql8gajwtrrdrs38kdzeersc", otherWif), err: ErrKeyMismatch},
+		{in: newInput("tb1pklh8lqax5l7m2ycypptv2emc4gata2dy28svnwcp9u32wlkenvsspcvhsr", otherWif), err: ErrKeyMismatch},
+		{in: newInput("2NF33rckfiQTiE5Guk5ufUdwms8PgmtnEdc", otherWif), err: ErrUnsupportedScript},
+		{in: newInput(ms.p2wsh, ms.wifs[0]), err: ErrUnsupportedScript},
+	}
+	for _, test := range tests {
+		if _, err := Transfer([]*TxInput{test.in}, outs, network); !errors.Is(err, test.err) {
+			t.Errorf("%s: expected %v, got %v", test.in.Address, test.err, err)
+		}
+	}
+
+	// p2pk, addressed by its public key
+	wif, _ := btcutil.DecodeWIF(ms.wifs[0])
+	pubKey := wif.PrivKey.PubKey().SerializeCompressed()
+	p2pk, _ := txscript.NewScriptBuilder().AddData(pubKey).AddOp(txscript.OP_CHECKSIG).Script()
+	prevTx := wire.NewMsgTx(txVersion)
+	prevTx.AddTxIn(wire.NewTxIn(&wire.OutPoint{Hash: chainhash.Hash{1}}, nil, nil))
+	prevTx.AddTxOut(wire.NewTxOut(100000, p2pk))
+	in := newInput(hex.EncodeToString(pubKey), ms.wifs[0])
+	in.TxId = prevTx.TxHash().String()
+	in.NonWitnessUtxo, _ = getTxHex(prevTx)
+	txHex, err := Transfer([]*TxInput{in}, outs, network)
+	if err != nil {
+		t.Fatal(err)
+	}
+	verifyTestTx(t, txHex, []*TxInput{in})
+}
+
+func TestInscribeKeyMismatch(t *testing.T) {
+	request := newTestInscriptionRequest()
+	request.CommitTxPrevOutputList[1].Address = "tb1qtsq9c4fje6qsmheql8gajwtrrdrs38kdzeersc"
+	request.CommitTxPrevOutputList[1].PrivateKey = newTestMultisig(t).wifs[1]
+	if _, err := Inscribe(&chaincfg.TestNet3Params, request); !errors.Is(err, ErrKeyMismatch) {
+		t.Fatalf("expected ErrKeyMismatch, got %v", err)
+	}
+}
+
+func TestPubKeyAddress(t *testing.T) {
+	wif, _ := btcutil.DecodeWIF("cPnvkvUYyHcSSS26iD1dkrJdV7k1RoUqJLhn3CYxpo398PdLVE22")
+	tests := map[ScriptType]string{
+		ScriptTypeP2TR:       "tb1pklh8lqax5l7m2ycypptv2emc4gata2dy28svnwcp9u32wlkenvsspcvhsr",
+		ScriptTypeP2WPKH:     "tb1qtsq9c4fje6qsmheql8gajwtrrdrs38kdzeersc",
+		ScriptTypeP2SHP2WPKH: "2NF33rckfiQTiE5Guk5ufUdwms8PgmtnEdc",
+		ScriptTypeP2PKH:      "mouQtmBWDS7JnT65Grj2tPzdSmGKJgRMhE",
+	}
+	for scriptType, expected := range tests {
+		address, err := PubKeyAddress(wif.PrivKey.PubKey(), scriptType, &chaincfg.TestNet3Params)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if address != expected {
+			t.Errorf("%s: expected %s, got %s", scriptType, expected, address)
+		}
+	}
+	if _, err := PubKeyAddress(wif.PrivKey.PubKey(), ScriptTypeP2WSH, &chaincfg.TestNet3Params); !errors.Is(err, ErrUnsupportedScript) {
+		t.Errorf("expected ErrUnsupportedScript, got %v", err)
+	}
+}
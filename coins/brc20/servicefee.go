@@ -0,0 +1,49 @@
+package brc20
+
+import (
+	"errors"
+)
+
+var (
+	ErrInvalidServiceFee = errors.New("invalid service fee")
+)
+
+// ServiceFee is a platform fee paid by the commit tx, of PerInscription sats for every
+// inscription plus BasisPoints of the inscribed value, and at least Minimum sats.
+// The inscribed value is what the commit tx sends to the reveal txs: the inscription
+// outputs, the reveal outputs and the reveal fees.
+type ServiceFee struct {
+	Address        string `json:"address"`
+	PerInscription int64  `json:"perInscription"`
+	// BasisPoints is the share of the inscribed value in 1/10000, e.g. 250 for 2.5%
+	BasisPoints int64 `json:"basisPoints"`
+	Minimum     int64 `json:"minimum"`
+}
+
+func (serviceFee *ServiceFee) amount(inscriptions int, inscribedValue int64) int64 {
+	if serviceFee == nil {
+		return 0
+	}
+	amount := serviceFee.PerInscription*int64(inscriptions) + inscribedValue*serviceFee.BasisPoints/10000
+	if amount < serviceFee.Minimum {
+		amount = serviceFee.Minimum
+	}
+	return amount
+}
+
+// commitOutputs returns the service fee output followed by CommitOutputs.
+func (request *InscriptionRequest) commitOutputs(inscribedValue int64) ([]*TxOutput, error) {
+	serviceFee := request.ServiceFee
+	if serviceFee == nil {
+		return request.CommitOutputs, nil
+	}
+	if serviceFee.PerInscription < 0 || serviceFee.BasisPoints < 0 || serviceFee.BasisPoints > 10000 ||
+		serviceFee.Minimum < 0 || serviceFee.Address == "" {
+		return nil, ErrInvalidServiceFee
+	}
+	amount := serviceFee.amount(len(request.InscriptionDataList), inscribedValue)
+	if amount == 0 {
+		return request.CommitOutputs, nil
+	}
+	return append([]*TxOutput{{Address: serviceFee.Address, Amount: amount}}, request.CommitOutputs...), nil
+}
@@ -0,0 +1,94 @@
+package brc20
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/btcsuite/btcd/chaincfg"
+)
+
+func TestServiceFee(t *testing.T) {
+	for _, test := range []struct {
+		serviceFee     *ServiceFee
+		inscriptions   int
+		inscribedValue int64
+		amount         int64
+	}{
+		{nil, 2, 10000, 0},
+		{&ServiceFee{PerInscription: 1000}, 2, 10000, 2000},
+		{&ServiceFee{PerInscription: 1000, Minimum: 5000}, 2, 10000, 5000},
+		{&ServiceFee{PerInscription: 1000, Minimum: 5000}, 6, 10000, 6000},
+		{&ServiceFee{BasisPoints: 250}, 2, 100000, 2500},
+		{&ServiceFee{PerInscription: 1000, BasisPoints: 250}, 2, 100000, 4500},
+		{&ServiceFee{PerInscription: 1000, BasisPoints: 250, Minimum: 5000}, 2, 100000, 5000},
+	} {
+		if amount := test.serviceFee.amount(test.inscriptions, test.inscribedValue); amount != test.amount {
+			t.Errorf("%+v, %d inscriptions of %d: got %d, want %d", test.serviceFee, test.inscriptions,
+				test.inscribedValue, amount, test.amount)
+		}
+	}
+
+	network := &chaincfg.TestNet3Params
+	request := newTestInscriptionRequest()
+	request.ServiceFee = &ServiceFee{
+		Address:        "tb1qtsq9c4fje6qsmheql8gajwtrrdrs38kdzeersc",
+		PerInscription: 1000,
+		Minimum:        3000,
+	}
+	request.CommitOutputs = []*TxOutput{{Address: "mouQtmBWDS7JnT65Grj2tPzdSmGKJgRMhE", Amount: 1000}}
+	txs, err := Inscribe(network, request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if txs.ServiceFee != 3000 {
+		t.Fatalf("service fee %d", txs.ServiceFee)
+	}
+	commitTx, _ := newTxFromHex(txs.CommitTx)
+	serviceFeePkScript, _ := AddrToPkScript(request.ServiceFee.Address, network)
+	if out := commitTx.TxOut[2]; out.Value != 3000 || string(out.PkScript) != string(serviceFeePkScript) {
+		t.Fatalf("unexpected service fee output %d", out.Value)
+	}
+	if commitTx.TxOut[3].Value != 1000 {
+		t.Fatal("commit outputs must follow the service fee output")
+	}
+	inputValue := int64(0)
+	for _, prevOutput := range request.CommitTxPrevOutputList {
+		inputValue += prevOutput.Amount
+	}
+	for _, out := range commitTx.TxOut {
+		inputValue -= out.Value
+	}
+	if txs.CommitTxFee != inputValue {
+		t.Fatalf("commit fee %d includes the service fee, network fee is %d", txs.CommitTxFee, inputValue)
+	}
+
+	// 1000 sats per inscription plus 1% of the reveal funding outputs
+	request.ServiceFee = &ServiceFee{
+		Address:        "tb1qtsq9c4fje6qsmheql8gajwtrrdrs38kdzeersc",
+		PerInscription: 1000,
+		BasisPoints:    100,
+	}
+	if txs, err = Inscribe(network, request); err != nil {
+		t.Fatal(err)
+	}
+	commitTx, _ = newTxFromHex(txs.CommitTx)
+	inscriptions := len(request.InscriptionDataList)
+	inscribedValue := int64(0)
+	for _, out := range commitTx.TxOut[:inscriptions] {
+		inscribedValue += out.Value
+	}
+	expected := 1000*int64(inscriptions) + inscribedValue/100
+	if txs.ServiceFee != expected || commitTx.TxOut[inscriptions].Value != expected {
+		t.Fatalf("service fee %d, output %d, want %d", txs.ServiceFee, commitTx.TxOut[inscriptions].Value, expected)
+	}
+
+	request.ServiceFee.BasisPoints = 10001
+	if _, err := Inscribe(network, request); !errors.Is(err, ErrInvalidServiceFee) {
+		t.Fatal(err)
+	}
+	request.ServiceFee.BasisPoints = 0
+	request.ServiceFee.Address = ""
+	if _, err := Inscribe(network, request); !errors.Is(err, ErrInvalidServiceFee) {
+		t.Fatal(err)
+	}
+}
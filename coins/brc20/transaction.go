@@ -3,12 +3,16 @@ package brc20
 import (
 	"bytes"
 	"encoding/hex"
+	"errors"
+	"fmt"
 
+	"github.com/btcsuite/btcd/btcec/v2"
 	"github.com/btcsuite/btcd/btcec/v2/schnorr"
 	"github.com/btcsuite/btcd/btcutil"
 	"github.com/btcsuite/btcd/btcutil/psbt"
 	"github.com/btcsuite/btcd/chaincfg"
 	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/mempool"
 	"github.com/btcsuite/btcd/txscript"
 	"github.com/btcsuite/btcd/wire"
 )
@@ -20,33 +24,189 @@ type TxInput struct {
 	Address        string
 	PrivateKey     string
 	NonWitnessUtxo string // legacy address need
+	// IsPadding marks a padding utxo from PrepareDummyUTXOs, which Transfer refuses to spend
+	IsPadding bool
+	// TapLeafScript and ControlBlock spend a taproot output through a script path.
+	// The witness is the signature, left out when PrivateKey is empty, then
+	// ExtraWitness, the script and the control block.
+	TapLeafScript []byte
+	ControlBlock  []byte
+	ExtraWitness  [][]byte
+	// TapMerkleRoot is the script tree root for a key path spend of an output that
+	// commits to scripts, empty for BIP-86 outputs
+	TapMerkleRoot []byte
+	// WitnessScript is the multisig script of a p2wsh or p2sh-p2wsh output,
+	// RedeemScript the one of a p2sh output. PrivateKeys are signing keys besides
+	// PrivateKey.
+	WitnessScript []byte
+	RedeemScript  []byte
+	PrivateKeys   []string
+	// MuSig2PubKeys are the keys whose MuSig2 aggregate is the internal key of a
+	// taproot output. The input is left unsigned for the MuSig2 signing rounds.
+	MuSig2PubKeys []string
+	// DerivationPath is the BIP-32 path of PrivateKey, set from it by a wallet when
+	// PrivateKey is empty
+	DerivationPath string
 }
 
 type TxOutput struct {
 	Address string
 	Amount  int64
+	// PkScript is used instead of Address when set, e.g. for a runestone
+	PkScript []byte
+}
+
+type TransferResult struct {
+	Tx     string `json:"tx"`
+	Fee    int64  `json:"fee"`
+	VSize  int64  `json:"vSize"`
+	Change int64  `json:"change"`
 }
 
 const (
 	txVersion = 2
 	nLockTime = 0
+
+	// MaxFeeRate is bitcoin core's default -maxfeerate (0.1 BTC/kvB) in sat/vB, used by DefaultFeeGuard.
+	MaxFeeRate = FeeRate(10000)
 )
 
-func Transfer(ins []*TxInput, outs []*TxOutput, network *chaincfg.Params) (string, error) {
+var (
+	ErrInvalidFeeRate = errors.New("invalid fee rate")
+	ErrInvalidTapLeaf = errors.New("invalid tap leaf")
+)
+
+// input weight estimates for a single-key spend with a 72 byte DER signature
+const (
+	txOverheadWeight      = (4 + 1 + 1 + 4) * 4 // version, input and output count, locktime
+	segwitMarkerWeight    = 2
+	p2pkhInputWeight      = (32 + 4 + 1 + 107 + 4) * 4
+	p2pkInputWeight       = (32 + 4 + 1 + 73 + 4) * 4
+	p2shP2wpkhInputWeight = (32+4+1+23+4)*4 + 1 + 73 + 34
+	p2wpkhInputWeight     = (32+4+1+4)*4 + 1 + 73 + 34
+	p2trInputWeight       = (32+4+1+4)*4 + 1 + 65
+)
+
+// TxOption is a per-call option of Transfer, TransferWithChange and ExtractTx.
+type TxOption func(*txOptions)
+
+type txOptions struct {
+	feeGuard      *FeeGuard
+	verifyScripts bool
+}
+
+// WithFeeGuard checks the fee against guard instead of DefaultFeeGuard.
+func WithFeeGuard(guard *FeeGuard) TxOption {
+	return func(options *txOptions) {
+		options.feeGuard = guard
+	}
+}
+
+// WithVerifyScripts runs every input of the transaction through the script engine
+// before returning it.
+func WithVerifyScripts() TxOption {
+	return func(options *txOptions) {
+		options.verifyScripts = true
+	}
+}
+
+func newTxOptions(opts []TxOption) *txOptions {
+	options := &txOptions{}
+	for _, opt := range opts {
+		opt(options)
+	}
+	options.feeGuard = options.feeGuard.orDefault()
+	return options
+}
+
+func Transfer(ins []*TxInput, outs []*TxOutput, network *chaincfg.Params, opts ...TxOption) (string, error) {
+	options := newTxOptions(opts)
+	bp, err := signTransfer(ins, outs, network)
+	if err != nil {
+		return "", err
+	}
+	for i, in := range ins {
+		pkScript, err := AddrToPkScript(in.Address, network)
+		if err != nil {
+			return "", err
+		}
+		multisig, err := in.multisig(pkScript)
+		if err != nil {
+			return "", err
+		}
+		if multisig != nil && len(multisig.privateKeys) < multisig.threshold {
+			return "", fmt.Errorf("%w: input %d has %d of %d, use TransferPSBT", ErrMultisigThreshold,
+				i, len(multisig.privateKeys), multisig.threshold)
+		}
+		if len(in.MuSig2PubKeys) > 0 {
+			return "", fmt.Errorf("%w: input %d, use TransferPSBT", ErrMuSig2Unsigned, i)
+		}
+		if err = finalizeInput(bp, i); err != nil {
+			return "", err
+		}
+	}
+
+	buyerSignedTx, err := psbt.Extract(bp)
+	if err != nil {
+		return "", err
+	}
+
+	inputValue := int64(0)
+	for _, in := range ins {
+		inputValue += in.Amount
+	}
+	if err = options.feeGuard.checkTx("transfer", 0, buyerSignedTx, inputValue); err != nil {
+		return "", err
+	}
+	if options.verifyScripts {
+		prevOuts, complete, err := psbtPrevOuts(bp)
+		if err != nil {
+			return "", err
+		}
+		if !complete {
+			return "", fmt.Errorf("%w: the scripts cannot be verified", ErrMissingUtxo)
+		}
+		if err = verifyTx("transfer", 0, buyerSignedTx, prevOuts); err != nil {
+			return "", err
+		}
+	}
+
+	var buf bytes.Buffer
+	if err = buyerSignedTx.Serialize(&buf); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(buf.Bytes()), nil
+}
+
+// TransferPSBT works like Transfer, but returns the signed PSBT in base64 without
+// finalizing it, e.g. for multisig inputs the other keys sign with SignPSBT.
+func TransferPSBT(ins []*TxInput, outs []*TxOutput, network *chaincfg.Params) (string, error) {
+	bp, err := signTransfer(ins, outs, network)
+	if err != nil {
+		return "", err
+	}
+	return bp.B64Encode()
+}
+
+func signTransfer(ins []*TxInput, outs []*TxOutput, network *chaincfg.Params) (*psbt.Packet, error) {
 	var inputs []*wire.OutPoint
 	var nSequences []uint32
 	prevOuts := make(map[wire.OutPoint]*wire.TxOut)
 	for _, in := range ins {
+		if in.IsPadding {
+			return nil, fmt.Errorf("%w: %s:%d", ErrPaddingUtxo, in.TxId, in.VOut)
+		}
 		txHash, err := chainhash.NewHashFromStr(in.TxId)
 		if err != nil {
-			return "", err
+			return nil, err
 		}
 		prevOut := wire.NewOutPoint(txHash, in.VOut)
 		inputs = append(inputs, prevOut)
 
 		prevPkScript, err := AddrToPkScript(in.Address, network)
 		if err != nil {
-			return "", err
+			return nil, err
 		}
 		witnessUtxo := wire.NewTxOut(in.Amount, prevPkScript)
 		prevOuts[*prevOut] = witnessUtxo
@@ -56,60 +216,184 @@ func Transfer(ins []*TxInput, outs []*TxOutput, network *chaincfg.Params) (strin
 
 	var outputs []*wire.TxOut
 	for _, out := range outs {
-		pkScript, err := AddrToPkScript(out.Address, network)
+		pkScript, err := out.pkScript(network)
 		if err != nil {
-			return "", err
+			return nil, err
 		}
 		outputs = append(outputs, wire.NewTxOut(out.Amount, pkScript))
 	}
 
 	bp, err := psbt.New(inputs, outputs, txVersion, nLockTime, nSequences)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
 	updater, err := psbt.NewUpdater(bp)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
 	prevOutputFetcher := txscript.NewMultiPrevOutFetcher(prevOuts)
 
 	for i, in := range ins {
 		if err = signInput(updater, i, in, prevOutputFetcher, txscript.SigHashAll, network); err != nil {
-			return "", err
+			return nil, err
 		}
-		if err = psbt.Finalize(bp, i); err != nil {
-			return "", err
+	}
+	return bp, nil
+}
+
+// TransferWithChange works like Transfer, but estimates the transaction size from the
+// input script types and sends whatever is left after feeRate to changeAddress.
+// The change output is left out when it would be dust, its value going to the fee.
+func TransferWithChange(ins []*TxInput, outs []*TxOutput, changeAddress string, feeRate FeeRate, network *chaincfg.Params,
+	opts ...TxOption) (*TransferResult, error) {
+	if err := newTxOptions(opts).feeGuard.checkFeeRate(feeRate); err != nil {
+		return nil, err
+	}
+
+	totalIn, totalOut := int64(0), int64(0)
+	var inPkScripts [][]byte
+	for _, in := range ins {
+		pkScript, err := AddrToPkScript(in.Address, network)
+		if err != nil {
+			return nil, err
+		}
+		inPkScripts = append(inPkScripts, pkScript)
+		totalIn += in.Amount
+	}
+	var txOuts []*wire.TxOut
+	for _, out := range outs {
+		pkScript, err := out.pkScript(network)
+		if err != nil {
+			return nil, err
+		}
+		txOuts = append(txOuts, wire.NewTxOut(out.Amount, pkScript))
+		totalOut += out.Amount
+	}
+	var inWeights []int64
+	for i, in := range ins {
+		inWeight, err := in.estimateWeight(inPkScripts[i])
+		if err != nil {
+			return nil, err
 		}
+		inWeights = append(inWeights, inWeight)
 	}
+	weight := estimateTxWeightFor(inPkScripts, inWeights, txOuts)
 
-	buyerSignedTx, err := psbt.Extract(bp)
+	changePkScript, err := AddrToPkScript(changeAddress, network)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
+	changeOut := wire.NewTxOut(0, changePkScript)
+	weightWithChange := weight + int64(changeOut.SerializeSize())*4
 
-	var buf bytes.Buffer
-	if err = buyerSignedTx.Serialize(&buf); err != nil {
-		return "", err
+	change := totalIn - totalOut - feeRate.FeeForWeight(weightWithChange)
+	changeOut.Value = change
+	if change > 0 && !mempool.IsDust(changeOut, mempool.DefaultMinRelayTxFee) {
+		outs = append(append([]*TxOutput{}, outs...), &TxOutput{Address: changeAddress, Amount: change})
+	} else {
+		change = 0
+		if totalIn-totalOut < feeRate.FeeForWeight(weight) {
+			return nil, ErrInsufficientBalance
+		}
 	}
 
-	return hex.EncodeToString(buf.Bytes()), nil
+	txHex, err := Transfer(ins, outs, network, opts...)
+	if err != nil {
+		return nil, err
+	}
+	tx, err := newTxFromHex(txHex)
+	if err != nil {
+		return nil, err
+	}
+	return &TransferResult{
+		Tx:     txHex,
+		Fee:    totalIn - totalOut - change,
+		VSize:  mempool.GetTxVirtualSize(btcutil.NewTx(tx)),
+		Change: change,
+	}, nil
 }
 
-func signInput(updater *psbt.Updater, i int, in *TxInput, prevOutFetcher *txscript.MultiPrevOutFetcher, hashType txscript.SigHashType, network *chaincfg.Params) error {
-	wif, err := btcutil.DecodeWIF(in.PrivateKey)
-	if err != nil {
-		return err
+// estimateTxWeight estimates the weight of a tx spending single-key inputs.
+func estimateTxWeight(inPkScripts [][]byte, outs []*wire.TxOut) (int64, error) {
+	var inWeights []int64
+	for _, pkScript := range inPkScripts {
+		inWeight, err := estimateInputWeight(pkScript)
+		if err != nil {
+			return 0, err
+		}
+		inWeights = append(inWeights, inWeight)
+	}
+	return estimateTxWeightFor(inPkScripts, inWeights, outs), nil
+}
+
+// estimateTxWeightFor estimates the weight of a tx whose inputs, paying to
+// inPkScripts, weigh inWeights.
+func estimateTxWeightFor(inPkScripts [][]byte, inWeights []int64, outs []*wire.TxOut) int64 {
+	weight := int64(txOverheadWeight)
+	hasWitness := false
+	for i, pkScript := range inPkScripts {
+		if !txscript.IsPayToPubKeyHash(pkScript) {
+			hasWitness = true
+		}
+		weight += inWeights[i]
 	}
-	privKey := wif.PrivKey
+	if hasWitness {
+		weight += segwitMarkerWeight
+	}
+	for _, out := range outs {
+		weight += int64(out.SerializeSize()) * 4
+	}
+	return weight
+}
 
+// estimateWeight estimates the weight of spending in, which pays to pkScript,
+// including script path and multisig spends.
+func (in *TxInput) estimateWeight(pkScript []byte) (int64, error) {
+	if multisig, err := newMultisigInput(pkScript, in.RedeemScript, in.WitnessScript, nil); err == nil {
+		return multisig.estimateWeight(), nil
+	}
+	if len(in.TapLeafScript) == 0 {
+		return estimateInputWeight(pkScript)
+	}
+	var witness wire.TxWitness
+	if in.PrivateKey != "" {
+		witness = append(witness, make([]byte, schnorr.SignatureSize+1))
+	}
+	witness = append(witness, in.ExtraWitness...)
+	witness = append(witness, in.TapLeafScript, in.ControlBlock)
+	return (32+4+1+4)*4 + int64(witness.SerializeSize()), nil
+}
+
+// estimateInputWeight estimates the weight of a single-key spend of pkScript. Such
+// p2sh outputs are p2sh-p2wpkh; p2wsh and unknown outputs have no single-key spend.
+func estimateInputWeight(pkScript []byte) (int64, error) {
+	switch scriptType := ClassifyScript(pkScript, nil); scriptType {
+	case ScriptTypeP2TR:
+		return p2trInputWeight, nil
+	case ScriptTypeP2WPKH:
+		return p2wpkhInputWeight, nil
+	case ScriptTypeP2PKH:
+		return p2pkhInputWeight, nil
+	case ScriptTypeP2SH:
+		return p2shP2wpkhInputWeight, nil
+	case ScriptTypeP2PK:
+		return p2pkInputWeight, nil
+	default:
+		return 0, fmt.Errorf("%w: no weight estimate for %s output %x", ErrUnsupportedScript, scriptType, pkScript)
+	}
+}
+
+func signInput(updater *psbt.Updater, i int, in *TxInput, prevOutFetcher *txscript.MultiPrevOutFetcher, hashType txscript.SigHashType, network *chaincfg.Params) error {
 	prevPkScript, err := AddrToPkScript(in.Address, network)
 	if err != nil {
 		return err
 	}
 
-	if txscript.IsPayToPubKeyHash(prevPkScript) {
+	// legacy p2pk, p2pkh and p2sh multisig inputs carry the whole previous tx
+	if txscript.IsPayToPubKeyHash(prevPkScript) || txscript.IsPayToPubKey(prevPkScript) ||
+		len(in.RedeemScript) > 0 && len(in.WitnessScript) == 0 {
 		prevTx := wire.NewMsgTx(txVersion)
 		var txBytes []byte
 		if txBytes, err = hex.DecodeString(in.NonWitnessUtxo); err != nil {
@@ -132,23 +416,75 @@ func signInput(updater *psbt.Updater, i int, in *TxInput, prevOutFetcher *txscri
 	if err = updater.AddInSighashType(hashType, i); err != nil {
 		return err
 	}
+	if len(in.TapLeafScript) > 0 {
+		return signTapLeaf(updater, i, in, prevPkScript, prevOutFetcher, hashType)
+	}
+	multisig, err := in.multisig(prevPkScript)
+	if err != nil {
+		return err
+	}
+	if multisig != nil {
+		// cosigners without a key here sign from the scripts in the PSBT
+		if len(multisig.witnessScript) > 0 {
+			if err = updater.AddInWitnessScript(multisig.witnessScript, i); err != nil {
+				return err
+			}
+		}
+		if len(multisig.redeemScript) > 0 {
+			if err = updater.AddInRedeemScript(multisig.redeemScript, i); err != nil {
+				return err
+			}
+		}
+		return multisig.signPSBT(updater, i, prevOutFetcher, hashType)
+	}
+	internalKey, err := in.musig2InternalKey(prevPkScript)
+	if err != nil {
+		return err
+	}
+	if internalKey != nil {
+		updater.Upsbt.Inputs[i].TaprootInternalKey = internalKey
+		if len(in.TapMerkleRoot) > 0 {
+			updater.Upsbt.Inputs[i].TaprootMerkleRoot = in.TapMerkleRoot
+		}
+		return nil
+	}
+
+	wif, err := btcutil.DecodeWIF(in.PrivateKey)
+	if err != nil {
+		return err
+	}
+	return signPInput(updater, i, wif.PrivKey, prevPkScript, in.Amount, prevOutFetcher, hashType, in.TapMerkleRoot)
+}
 
-	if txscript.IsPayToTaproot(prevPkScript) {
+// signPInput adds the signature of privKey for input i, which spends a single-key
+// output with prevPkScript, to the PSBT. tapMerkleRoot is the script tree root of a
+// taproot output, nil for BIP-86.
+func signPInput(updater *psbt.Updater, i int, privKey *btcec.PrivateKey, prevPkScript []byte, amount int64,
+	prevOutFetcher txscript.PrevOutputFetcher, hashType txscript.SigHashType, tapMerkleRoot []byte) error {
+	scriptType, err := keyScriptType(prevPkScript, privKey, tapMerkleRoot)
+	if err != nil {
+		return fmt.Errorf("input %d: %w", i, err)
+	}
+	switch scriptType {
+	case ScriptTypeP2TR:
 		internalPubKey := schnorr.SerializePubKey(privKey.PubKey())
 		updater.Upsbt.Inputs[i].TaprootInternalKey = internalPubKey
+		if len(tapMerkleRoot) > 0 {
+			updater.Upsbt.Inputs[i].TaprootMerkleRoot = tapMerkleRoot
+		}
 
 		sigHashes := txscript.NewTxSigHashes(updater.Upsbt.UnsignedTx, prevOutFetcher)
 		if hashType == txscript.SigHashAll {
 			hashType = txscript.SigHashDefault
 		}
-		witness, err := txscript.TaprootWitnessSignature(updater.Upsbt.UnsignedTx, sigHashes,
-			i, in.Amount, prevPkScript, hashType, privKey)
+		sig, err := txscript.RawTxInTaprootSignature(updater.Upsbt.UnsignedTx, sigHashes,
+			i, amount, prevPkScript, tapMerkleRoot, hashType, privKey)
 		if err != nil {
 			return err
 		}
 
-		updater.Upsbt.Inputs[i].TaprootKeySpendSig = witness[0]
-	} else if txscript.IsPayToPubKeyHash(prevPkScript) {
+		updater.Upsbt.Inputs[i].TaprootKeySpendSig = appendTaprootSigHashType(sig, hashType)
+	case ScriptTypeP2PKH:
 		signature, err := txscript.RawTxInSignature(updater.Upsbt.UnsignedTx, i, prevPkScript, hashType, privKey)
 		if err != nil {
 			return err
@@ -157,7 +493,16 @@ func signInput(updater *psbt.Updater, i int, in *TxInput, prevOutFetcher *txscri
 		if err != nil {
 			return err
 		}
-	} else {
+	case ScriptTypeP2PK:
+		// the finalizer would add the public key as for p2pkh
+		signature, err := txscript.RawTxInSignature(updater.Upsbt.UnsignedTx, i, prevPkScript, hashType, privKey)
+		if err != nil {
+			return err
+		}
+		if updater.Upsbt.Inputs[i].FinalScriptSig, err = txscript.NewScriptBuilder().AddData(signature).Script(); err != nil {
+			return err
+		}
+	case ScriptTypeP2WPKH, ScriptTypeP2SHP2WPKH:
 		pubKeyBytes := privKey.PubKey().SerializeCompressed()
 		sigHashes := txscript.NewTxSigHashes(updater.Upsbt.UnsignedTx, prevOutFetcher)
 
@@ -165,12 +510,12 @@ func signInput(updater *psbt.Updater, i int, in *TxInput, prevOutFetcher *txscri
 		if err != nil {
 			return err
 		}
-		signature, err := txscript.RawTxInWitnessSignature(updater.Upsbt.UnsignedTx, sigHashes, i, in.Amount, script, hashType, privKey)
+		signature, err := txscript.RawTxInWitnessSignature(updater.Upsbt.UnsignedTx, sigHashes, i, amount, script, hashType, privKey)
 		if err != nil {
 			return err
 		}
 
-		if txscript.IsPayToScriptHash(prevPkScript) {
+		if scriptType == ScriptTypeP2SHP2WPKH {
 			redeemScript, err := PayToWitnessPubKeyHashScript(btcutil.Hash160(pubKeyBytes))
 			if err != nil {
 				return err
@@ -189,8 +534,78 @@ func signInput(updater *psbt.Updater, i int, in *TxInput, prevOutFetcher *txscri
 	return nil
 }
 
+// signTapLeaf spends input i through the tap leaf of in. Without extra witness
+// elements the signature goes in the PSBT script path fields, to be finalized
+// later; otherwise the final witness is written directly.
+func signTapLeaf(updater *psbt.Updater, i int, in *TxInput, prevPkScript []byte,
+	prevOutFetcher txscript.PrevOutputFetcher, hashType txscript.SigHashType) error {
+	if !txscript.IsPayToTaproot(prevPkScript) {
+		return fmt.Errorf("%w: %s is not a taproot address", ErrInvalidTapLeaf, in.Address)
+	}
+	controlBlock, err := txscript.ParseControlBlock(in.ControlBlock)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidTapLeaf, err)
+	}
+	if err = txscript.VerifyTaprootLeafCommitment(controlBlock, prevPkScript[2:], in.TapLeafScript); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidTapLeaf, err)
+	}
+	tapLeaf := txscript.NewBaseTapLeaf(in.TapLeafScript)
+	leafHash := tapLeaf.TapHash()
+	pInput := &updater.Upsbt.Inputs[i]
+	pInput.TaprootLeafScript = []*psbt.TaprootTapLeafScript{{
+		ControlBlock: in.ControlBlock,
+		Script:       in.TapLeafScript,
+		LeafVersion:  tapLeaf.LeafVersion,
+	}}
+
+	var witness wire.TxWitness
+	if in.PrivateKey != "" {
+		wif, err := btcutil.DecodeWIF(in.PrivateKey)
+		if err != nil {
+			return err
+		}
+		if hashType == txscript.SigHashAll {
+			hashType = txscript.SigHashDefault
+		}
+		sigHashes := txscript.NewTxSigHashes(updater.Upsbt.UnsignedTx, prevOutFetcher)
+		sig, err := txscript.RawTxInTapscriptSignature(updater.Upsbt.UnsignedTx, sigHashes, i, in.Amount,
+			prevPkScript, tapLeaf, hashType, wif.PrivKey)
+		if err != nil {
+			return err
+		}
+		witness = append(witness, sig)
+		if len(in.ExtraWitness) == 0 {
+			pInput.TaprootScriptSpendSig = []*psbt.TaprootScriptSpendSig{{
+				XOnlyPubKey: schnorr.SerializePubKey(wif.PrivKey.PubKey()),
+				LeafHash:    leafHash[:],
+				Signature:   sig[:schnorr.SignatureSize],
+				SigHash:     hashType,
+			}}
+			return nil
+		}
+	}
+	witness = append(witness, in.ExtraWitness...)
+	witness = append(witness, in.TapLeafScript, in.ControlBlock)
+	var buf bytes.Buffer
+	if err = psbt.WriteTxWitness(&buf, witness); err != nil {
+		return err
+	}
+	pInput.FinalScriptWitness = buf.Bytes()
+	return nil
+}
+
+// appendTaprootSigHashType appends the sighash type to a schnorr signature. btcd
+// v0.23 returns 64 byte signatures for every sighash type, which only verify as
+// SIGHASH_DEFAULT.
+func appendTaprootSigHashType(sig []byte, hashType txscript.SigHashType) []byte {
+	if hashType != txscript.SigHashDefault && len(sig) == schnorr.SignatureSize {
+		return append(sig, byte(hashType))
+	}
+	return sig
+}
+
 func AddrToPkScript(addr string, network *chaincfg.Params) ([]byte, error) {
-	address, err := btcutil.DecodeAddress(addr, network)
+	address, err := DecodeAddress(addr, network)
 	if err != nil {
 		return nil, err
 	}
@@ -198,6 +613,22 @@ func AddrToPkScript(addr string, network *chaincfg.Params) ([]byte, error) {
 	return txscript.PayToAddrScript(address)
 }
 
+// NullDataOutput returns a zero value OP_RETURN output carrying data.
+func NullDataOutput(data []byte) (*TxOutput, error) {
+	pkScript, err := txscript.NullDataScript(data)
+	if err != nil {
+		return nil, err
+	}
+	return &TxOutput{PkScript: pkScript}, nil
+}
+
+func (out *TxOutput) pkScript(network *chaincfg.Params) ([]byte, error) {
+	if len(out.PkScript) > 0 {
+		return out.PkScript, nil
+	}
+	return AddrToPkScript(out.Address, network)
+}
+
 func PayToPubKeyHashScript(pubKeyHash []byte) ([]byte, error) {
 	return txscript.NewScriptBuilder().
 		AddOp(txscript.OP_DUP).
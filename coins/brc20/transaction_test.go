@@ -1,9 +1,15 @@
 package brc20
 
 import (
+	"crypto/sha256"
+	"errors"
 	"testing"
 
+	"github.com/btcsuite/btcd/btcec/v2/schnorr"
+	"github.com/btcsuite/btcd/btcutil"
 	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
 )
 
 func TestTransfer(t *testing.T) {
@@ -73,3 +79,160 @@ func TestTransfer(t *testing.T) {
 	}
 	t.Log(signedTx)
 }
+
+func TestTransferWithChange(t *testing.T) {
+	network := &chaincfg.TestNet3Params
+
+	var inputs []*TxInput
+	// inscription
+	inputs = append(inputs, &TxInput{
+		TxId:       "46e3ce050474e6da80760a2a0b062836ff13e2a42962dc1c9b17b8f962444206",
+		VOut:       uint32(0),
+		Amount:     int64(546),
+		Address:    "tb1pklh8lqax5l7m2ycypptv2emc4gata2dy28svnwcp9u32wlkenvsspcvhsr",
+		PrivateKey: "cPnvkvUYyHcSSS26iD1dkrJdV7k1RoUqJLhn3CYxpo398PdLVE22",
+	})
+	inputs = append(inputs, &TxInput{
+		TxId:       "25b9d08a26c8d47795301dd47a861cff0459d14f27fbd41cffaca17d9aa20f87",
+		VOut:       uint32(0),
+		Amount:     int64(249352),
+		Address:    "tb1qtsq9c4fje6qsmheql8gajwtrrdrs38kdzeersc",
+		PrivateKey: "cPnvkvUYyHcSSS26iD1dkrJdV7k1RoUqJLhn3CYxpo398PdLVE22",
+	})
+	inputs = append(inputs, &TxInput{
+		TxId:       "d1696c10046ec8b2d938924f1923f1f2e1588095fbf3ea0f8cd640b51da51ba2",
+		VOut:       uint32(0),
+		Amount:     int64(10000),
+		Address:    "2NF33rckfiQTiE5Guk5ufUdwms8PgmtnEdc",
+		PrivateKey: "cPnvkvUYyHcSSS26iD1dkrJdV7k1RoUqJLhn3CYxpo398PdLVE22",
+	})
+
+	var outputs []*TxOutput
+	outputs = append(outputs, &TxOutput{
+		Address: "tb1qtsq9c4fje6qsmheql8gajwtrrdrs38kdzeersc",
+		Amount:  int64(546),
+	})
+
+	feeRate := FeeRate(5)
+	result, err := TransferWithChange(inputs, outputs, "tb1pklh8lqax5l7m2ycypptv2emc4gata2dy28svnwcp9u32wlkenvsspcvhsr", feeRate, network)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Change == 0 {
+		t.Fatal("expected a change output")
+	}
+	if result.Fee < feeRate.FeeForVSize(result.VSize) {
+		t.Fatalf("fee %d below %v sat/vB for %d vB", result.Fee, feeRate, result.VSize)
+	}
+	if result.Fee > feeRate.FeeForVSize(result.VSize+2) {
+		t.Fatalf("fee %d overpays %v sat/vB for %d vB", result.Fee, feeRate, result.VSize)
+	}
+	t.Log(result.Tx)
+
+	if _, err = TransferWithChange(inputs, outputs, "tb1qtsq9c4fje6qsmheql8gajwtrrdrs38kdzeersc", 100000, network); !errors.Is(err, ErrAbsurdFeeRate) {
+		t.Fatalf("expected ErrAbsurdFeeRate, got %v", err)
+	}
+	if _, err = TransferWithChange(inputs, outputs, "tb1qtsq9c4fje6qsmheql8gajwtrrdrs38kdzeersc", 2000, network); !errors.Is(err, ErrInsufficientBalance) {
+		t.Fatalf("expected ErrInsufficientBalance, got %v", err)
+	}
+	for _, feeRate := range []FeeRate{0, -1} {
+		if _, err = TransferWithChange(inputs, outputs, "tb1qtsq9c4fje6qsmheql8gajwtrrdrs38kdzeersc", feeRate, network); !errors.Is(err, ErrInvalidFeeRate) {
+			t.Fatalf("fee rate %v: expected ErrInvalidFeeRate, got %v", feeRate, err)
+		}
+	}
+
+	// a p2wsh input without its witness script can't be sized
+	p2wshInput := &TxInput{
+		TxId:       "25b9d08a26c8d47795301dd47a861cff0459d14f27fbd41cffaca17d9aa20f87",
+		VOut:       uint32(1),
+		Amount:     int64(10000),
+		Address:    "tb1qrp33g0q5c5txsp9arysrx4k6zdkfs4nce4xj0gdcccefvpysxf3q0sl5k7",
+		PrivateKey: "cPnvkvUYyHcSSS26iD1dkrJdV7k1RoUqJLhn3CYxpo398PdLVE22",
+	}
+	if _, err = TransferWithChange(append(inputs, p2wshInput), outputs, "tb1qtsq9c4fje6qsmheql8gajwtrrdrs38kdzeersc", feeRate, network); !errors.Is(err, ErrUnsupportedScript) {
+		t.Fatalf("expected ErrUnsupportedScript, got %v", err)
+	}
+}
+
+func TestTransferTapLeaf(t *testing.T) {
+	network := &chaincfg.TestNet3Params
+	const wif = "cPnvkvUYyHcSSS26iD1dkrJdV7k1RoUqJLhn3CYxpo398PdLVE22"
+	privateKey, _ := btcutil.DecodeWIF(wif)
+	internalKey := privateKey.PrivKey.PubKey()
+	xOnlyKey := schnorr.SerializePubKey(internalKey)
+
+	preimage := []byte("tap leaf preimage")
+	preimageHash := sha256.Sum256(preimage)
+	checkSig, _ := txscript.NewScriptBuilder().AddData(xOnlyKey).AddOp(txscript.OP_CHECKSIG).Script()
+	hashLockSig, _ := txscript.NewScriptBuilder().AddOp(txscript.OP_SHA256).AddData(preimageHash[:]).
+		AddOp(txscript.OP_EQUALVERIFY).AddData(xOnlyKey).AddOp(txscript.OP_CHECKSIG).Script()
+	hashLock, _ := txscript.NewScriptBuilder().AddOp(txscript.OP_SHA256).AddData(preimageHash[:]).
+		AddOp(txscript.OP_EQUAL).Script()
+	leaves := [][]byte{checkSig, hashLockSig, hashLock}
+	var tapLeaves []txscript.TapLeaf
+	for _, leaf := range leaves {
+		tapLeaves = append(tapLeaves, txscript.NewBaseTapLeaf(leaf))
+	}
+	tree := txscript.AssembleTaprootScriptTree(tapLeaves...)
+	rootHash := tree.RootNode.TapHash()
+	outputKey := txscript.ComputeTaprootOutputKey(internalKey, rootHash[:])
+	address, err := btcutil.NewAddressTaproot(schnorr.SerializePubKey(outputKey), network)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkScript, _ := AddrToPkScript(address.EncodeAddress(), network)
+
+	newInput := func() *TxInput {
+		return &TxInput{
+			TxId:       "25b9d08a26c8d47795301dd47a861cff0459d14f27fbd41cffaca17d9aa20f87",
+			VOut:       uint32(0),
+			Amount:     int64(100000),
+			Address:    address.EncodeAddress(),
+			PrivateKey: wif,
+		}
+	}
+	var inputs []*TxInput
+	for i, leaf := range leaves {
+		proof := tree.LeafMerkleProofs[i].ToControlBlock(internalKey)
+		controlBlock, err := proof.ToBytes()
+		if err != nil {
+			t.Fatal(err)
+		}
+		in := newInput()
+		in.TapLeafScript = leaf
+		in.ControlBlock = controlBlock
+		inputs = append(inputs, in)
+	}
+	inputs[1].ExtraWitness = [][]byte{preimage}
+	inputs[2].ExtraWitness = [][]byte{preimage}
+	inputs[2].PrivateKey = ""
+	keyPath := newInput()
+	keyPath.TapMerkleRoot = rootHash[:]
+	inputs = append(inputs, keyPath)
+
+	outs := []*TxOutput{{Address: "tb1qtsq9c4fje6qsmheql8gajwtrrdrs38kdzeersc", Amount: 50000}}
+	for i, in := range inputs {
+		result, err := TransferWithChange([]*TxInput{in}, outs, "tb1qtsq9c4fje6qsmheql8gajwtrrdrs38kdzeersc", 2, network)
+		if err != nil {
+			t.Fatalf("input %d: %v", i, err)
+		}
+		tx, err := newTxFromHex(result.Tx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		prevOuts := txscript.NewMultiPrevOutFetcher(nil)
+		prevOuts.AddPrevOut(tx.TxIn[0].PreviousOutPoint, wire.NewTxOut(in.Amount, pkScript))
+		if err := verifyInput(tx, 0, prevOuts); err != nil {
+			t.Fatalf("input %d: %v", i, err)
+		}
+		if result.Fee < FeeRate(2).FeeForVSize(result.VSize) {
+			t.Fatalf("input %d: fee %d below 2 sat/vB for %d vB", i, result.Fee, result.VSize)
+		}
+	}
+
+	wrongLeaf := inputs[0]
+	wrongLeaf.TapLeafScript = hashLock
+	if _, err = Transfer([]*TxInput{wrongLeaf}, outs, network); !errors.Is(err, ErrInvalidTapLeaf) {
+		t.Fatalf("expected ErrInvalidTapLeaf, got %v", err)
+	}
+}
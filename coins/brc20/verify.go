@@ -0,0 +1,55 @@
+package brc20
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+)
+
+var ErrScriptVerification = errors.New("script verification failed")
+
+type ScriptError struct {
+	TxType string
+	Index  int
+	Input  int
+	Err    error
+}
+
+func (e *ScriptError) Error() string {
+	return fmt.Sprintf("%s tx(index %d) input %d: %s: %v", e.TxType, e.Index, e.Input, ErrScriptVerification, e.Err)
+}
+
+// Is reports ErrScriptVerification, while Unwrap reaches the script engine error.
+func (e *ScriptError) Is(target error) bool {
+	return target == ErrScriptVerification
+}
+
+func (e *ScriptError) Unwrap() error {
+	return e.Err
+}
+
+// verifyTx runs the script of every input of tx, the index tx of type txType.
+func verifyTx(txType string, index int, tx *wire.MsgTx, prevOutFetcher txscript.PrevOutputFetcher) error {
+	for i := range tx.TxIn {
+		if err := verifyInput(tx, i, prevOutFetcher); err != nil {
+			return &ScriptError{TxType: txType, Index: index, Input: i, Err: err}
+		}
+	}
+	return nil
+}
+
+// verifyInput runs the script of input index of tx.
+func verifyInput(tx *wire.MsgTx, index int, prevOutFetcher txscript.PrevOutputFetcher) error {
+	prevOut := prevOutFetcher.FetchPrevOutput(tx.TxIn[index].PreviousOutPoint)
+	if prevOut == nil {
+		return fmt.Errorf("%w: input %d", ErrMissingUtxo, index)
+	}
+	engine, err := txscript.NewEngine(prevOut.PkScript, tx, index, txscript.StandardVerifyFlags, nil,
+		txscript.NewTxSigHashes(tx, prevOutFetcher), prevOut.Value, prevOutFetcher)
+	if err != nil {
+		return err
+	}
+	return engine.Execute()
+}
@@ -0,0 +1,65 @@
+package brc20
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+)
+
+func TestInscribeVerifyScripts(t *testing.T) {
+	request := newTestInscriptionRequest()
+	request.VerifyScripts = true
+	if _, err := Inscribe(&chaincfg.TestNet3Params, request); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestTransferVerifyScripts(t *testing.T) {
+	network := &chaincfg.TestNet3Params
+	var ins []*TxInput
+	for i, address := range []string{
+		"tb1pklh8lqax5l7m2ycypptv2emc4gata2dy28svnwcp9u32wlkenvsspcvhsr",
+		"tb1qtsq9c4fje6qsmheql8gajwtrrdrs38kdzeersc",
+		"2NF33rckfiQTiE5Guk5ufUdwms8PgmtnEdc",
+	} {
+		ins = append(ins, &TxInput{
+			TxId:       "25b9d08a26c8d47795301dd47a861cff0459d14f27fbd41cffaca17d9aa20f87",
+			VOut:       uint32(i),
+			Amount:     int64(100000),
+			Address:    address,
+			PrivateKey: "cPnvkvUYyHcSSS26iD1dkrJdV7k1RoUqJLhn3CYxpo398PdLVE22",
+		})
+	}
+	outs := []*TxOutput{{Address: "tb1qtsq9c4fje6qsmheql8gajwtrrdrs38kdzeersc", Amount: 299000}}
+	txHex, err := Transfer(ins, outs, network, WithVerifyScripts())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a signature no longer valid once the outputs change
+	tx, err := newTxFromHex(txHex)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx.TxOut[0].Value--
+	prevOuts := txscript.NewMultiPrevOutFetcher(nil)
+	for i, in := range ins {
+		pkScript, _ := AddrToPkScript(in.Address, network)
+		prevOuts.AddPrevOut(tx.TxIn[i].PreviousOutPoint, wire.NewTxOut(in.Amount, pkScript))
+	}
+	err = verifyTx("transfer", 0, tx, prevOuts)
+	if !errors.Is(err, ErrScriptVerification) {
+		t.Fatalf("expected ErrScriptVerification, got %v", err)
+	}
+	var scriptErr *ScriptError
+	if !errors.As(err, &scriptErr) || scriptErr.TxType != "transfer" || scriptErr.Input != 0 {
+		t.Fatalf("unexpected error %v", err)
+	}
+	var engineErr txscript.Error
+	if !errors.As(err, &engineErr) {
+		t.Fatalf("expected the script engine error, got %v", err)
+	}
+}
@@ -0,0 +1,227 @@
+package wallet
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/btcutil/hdkeychain"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/tyler-smith/go-bip39"
+
+	"wallet-coin-sdk/coins/brc20"
+)
+
+var (
+	ErrInvalidMnemonic    = errors.New("invalid mnemonic")
+	ErrInvalidExtendedKey = errors.New("invalid extended key")
+	ErrInvalidPath        = errors.New("invalid derivation path")
+)
+
+// Scheme is how a wallet derives the addresses of one type.
+type Scheme struct {
+	Purpose     uint32
+	AddressType brc20.ScriptType
+	// AccountInIndex puts the account in the address index, m/purpose'/coin'/0'/change/account,
+	// so each account has a single address
+	AccountInIndex bool
+}
+
+var (
+	BIP44 = Scheme{Purpose: 44, AddressType: brc20.ScriptTypeP2PKH}
+	BIP49 = Scheme{Purpose: 49, AddressType: brc20.ScriptTypeP2SHP2WPKH}
+	BIP84 = Scheme{Purpose: 84, AddressType: brc20.ScriptTypeP2WPKH}
+	BIP86 = Scheme{Purpose: 86, AddressType: brc20.ScriptTypeP2TR}
+
+	// Unisat uses the standard paths, m/86'/coin'/account'/change/index for taproot
+	UnisatTaproot      = BIP86
+	UnisatNativeSegwit = BIP84
+	// Xverse keeps ordinals on taproot and pays from p2sh-p2wpkh, with the account in the index
+	XverseOrdinals = Scheme{Purpose: 86, AddressType: brc20.ScriptTypeP2TR, AccountInIndex: true}
+	XversePayment  = Scheme{Purpose: 49, AddressType: brc20.ScriptTypeP2SHP2WPKH, AccountInIndex: true}
+)
+
+// Key is a derived key with its address.
+type Key struct {
+	Path       string `json:"path"`
+	Address    string `json:"address"`
+	PublicKey  string `json:"publicKey"`
+	PrivateKey string `json:"privateKey"`
+}
+
+type Wallet struct {
+	master  *hdkeychain.ExtendedKey
+	network *chaincfg.Params
+}
+
+// NewWallet returns the wallet of a BIP-39 mnemonic and optional passphrase.
+func NewWallet(mnemonic, passphrase string, network *chaincfg.Params) (*Wallet, error) {
+	seed, err := bip39.NewSeedWithErrorChecking(mnemonic, passphrase)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidMnemonic, err)
+	}
+	master, err := hdkeychain.NewMaster(seed, network)
+	if err != nil {
+		return nil, err
+	}
+	return &Wallet{master: master, network: network}, nil
+}
+
+// NewWalletFromExtendedKey returns the wallet of a master xprv, tprv on test networks.
+func NewWalletFromExtendedKey(xprv string, network *chaincfg.Params) (*Wallet, error) {
+	master, err := hdkeychain.NewKeyFromString(xprv)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidExtendedKey, err)
+	}
+	if !master.IsPrivate() || master.Depth() != 0 || !master.IsForNet(network) {
+		return nil, fmt.Errorf("%w: not a %s master private key", ErrInvalidExtendedKey, network.Name)
+	}
+	return &Wallet{master: master, network: network}, nil
+}
+
+// Derive returns the key at path, e.g. m/86'/0'/0'/0/0, with its address of addressType.
+func (w *Wallet) Derive(path string, addressType brc20.ScriptType) (*Key, error) {
+	wif, err := w.wif(path)
+	if err != nil {
+		return nil, err
+	}
+	address, err := brc20.PubKeyAddress(wif.PrivKey.PubKey(), addressType, w.network)
+	if err != nil {
+		return nil, err
+	}
+	return &Key{
+		Path:       path,
+		Address:    address,
+		PublicKey:  hex.EncodeToString(wif.PrivKey.PubKey().SerializeCompressed()),
+		PrivateKey: wif.String(),
+	}, nil
+}
+
+// Address returns the receive or change address index of account.
+func (w *Wallet) Address(scheme Scheme, account uint32, change bool, index uint32) (*Key, error) {
+	path, err := scheme.Path(w.network, account, change, index)
+	if err != nil {
+		return nil, err
+	}
+	return w.Derive(path, scheme.AddressType)
+}
+
+// AccountPublicKey returns the xpub, tpub on test networks, of account for watch-only use.
+func (w *Wallet) AccountPublicKey(scheme Scheme, account uint32) (string, error) {
+	if scheme.AccountInIndex {
+		account = 0
+	}
+	extendedKey, err := w.derive(accountPath(scheme, w.network, account))
+	if err != nil {
+		return "", err
+	}
+	extendedKey, err = extendedKey.Neuter()
+	if err != nil {
+		return "", err
+	}
+	return extendedKey.String(), nil
+}
+
+// ResolvePrevOutputs sets the PrivateKey of the outputs that have a DerivationPath
+// and no PrivateKey. A key the address does not pay to fails when signing.
+func (w *Wallet) ResolvePrevOutputs(prevOutputs []*brc20.PrevOutput) error {
+	for _, prevOutput := range prevOutputs {
+		if prevOutput.PrivateKey != "" || prevOutput.DerivationPath == "" {
+			continue
+		}
+		wif, err := w.wif(prevOutput.DerivationPath)
+		if err != nil {
+			return err
+		}
+		prevOutput.PrivateKey = wif.String()
+	}
+	return nil
+}
+
+// ResolveTxInputs is ResolvePrevOutputs for transfer inputs.
+func (w *Wallet) ResolveTxInputs(ins []*brc20.TxInput) error {
+	for _, in := range ins {
+		if in.PrivateKey != "" || in.DerivationPath == "" {
+			continue
+		}
+		wif, err := w.wif(in.DerivationPath)
+		if err != nil {
+			return err
+		}
+		in.PrivateKey = wif.String()
+	}
+	return nil
+}
+
+func (w *Wallet) wif(path string) (*btcutil.WIF, error) {
+	extendedKey, err := w.derive(path)
+	if err != nil {
+		return nil, err
+	}
+	privateKey, err := extendedKey.ECPrivKey()
+	if err != nil {
+		return nil, err
+	}
+	return btcutil.NewWIF(privateKey, w.network, true)
+}
+
+func (w *Wallet) derive(path string) (*hdkeychain.ExtendedKey, error) {
+	indexes, err := ParsePath(path)
+	if err != nil {
+		return nil, err
+	}
+	extendedKey := w.master
+	for _, index := range indexes {
+		if extendedKey, err = extendedKey.Derive(index); err != nil {
+			return nil, err
+		}
+	}
+	return extendedKey, nil
+}
+
+// Path returns the path of the receive or change address index of account.
+func (scheme Scheme) Path(network *chaincfg.Params, account uint32, change bool, index uint32) (string, error) {
+	chain := 0
+	if change {
+		chain = 1
+	}
+	if scheme.AccountInIndex {
+		if index != 0 {
+			return "", fmt.Errorf("%w: an account has a single address", ErrInvalidPath)
+		}
+		return fmt.Sprintf("%s/%d/%d", accountPath(scheme, network, 0), chain, account), nil
+	}
+	return fmt.Sprintf("%s/%d/%d", accountPath(scheme, network, account), chain, index), nil
+}
+
+func accountPath(scheme Scheme, network *chaincfg.Params, account uint32) string {
+	return fmt.Sprintf("m/%d'/%d'/%d'", scheme.Purpose, network.HDCoinType, account)
+}
+
+// ParsePath parses a BIP-32 path such as m/86'/0'/0'/0/0. Hardened indexes end
+// with ' or h.
+func ParsePath(path string) ([]uint32, error) {
+	elements := strings.Split(path, "/")
+	if elements[0] != "m" {
+		return nil, fmt.Errorf("%w: %s does not start with m", ErrInvalidPath, path)
+	}
+	var indexes []uint32
+	for _, element := range elements[1:] {
+		hardened := strings.HasSuffix(element, "'") || strings.HasSuffix(element, "h")
+		if hardened {
+			element = element[:len(element)-1]
+		}
+		index, err := strconv.ParseUint(element, 10, 32)
+		if err != nil || index >= hdkeychain.HardenedKeyStart {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidPath, path)
+		}
+		if hardened {
+			index += hdkeychain.HardenedKeyStart
+		}
+		indexes = append(indexes, uint32(index))
+	}
+	return indexes, nil
+}
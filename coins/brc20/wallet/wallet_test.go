@@ -0,0 +1,151 @@
+package wallet
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/btcsuite/btcd/chaincfg"
+
+	"wallet-coin-sdk/coins/brc20"
+)
+
+const testMnemonic = "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about"
+
+func TestWalletAddress(t *testing.T) {
+	w, err := NewWallet(testMnemonic, "", &chaincfg.MainNetParams)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// test vectors of BIP-44, BIP-49, BIP-84 and BIP-86
+	tests := []struct {
+		scheme  Scheme
+		change  bool
+		address string
+	}{
+		{scheme: BIP44, address: "1LqBGSKuX5yYUonjxT5qGfpUsXKYYWeabA"},
+		{scheme: BIP49, address: "37VucYSaXLCAsxYyAPfbSi9eh4iEcbShgf"},
+		{scheme: BIP84, address: "bc1qcr8te4kr609gcawutmrza0j4xv80jy8z306fyu"},
+		{scheme: BIP86, address: "bc1p5cyxnuxmeuwuvkwfem96lqzszd02n6xdcjrs20cac6yqjjwudpxqkedrcr"},
+		{scheme: BIP86, change: true, address: "bc1p3qkhfews2uk44qtvauqyr2ttdsw7svhkl9nkm9s9c3x4ax5h60wqwruhk7"},
+	}
+	for _, test := range tests {
+		key, err := w.Address(test.scheme, 0, test.change, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if key.Address != test.address {
+			t.Errorf("%s: expected %s, got %s", key.Path, test.address, key.Address)
+		}
+	}
+
+	xpub, err := w.AccountPublicKey(BIP86, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := "xpub6BgBgsespWvERF3LHQu6CnqdvfEvtMcQjYrcRzx53QJjSxarj2afYWcLteoGVky7D3UKDP9QyrLprQ3VCECoY49yfdDEHGCtMMj92pReUsQ"; xpub != expected {
+		t.Errorf("expected %s, got %s", expected, xpub)
+	}
+}
+
+func TestSchemePath(t *testing.T) {
+	network := &chaincfg.TestNet3Params
+	tests := []struct {
+		scheme  Scheme
+		account uint32
+		index   uint32
+		path    string
+	}{
+		{scheme: UnisatTaproot, account: 1, index: 2, path: "m/86'/1'/1'/0/2"},
+		{scheme: XverseOrdinals, account: 1, path: "m/86'/1'/0'/0/1"},
+		{scheme: XversePayment, account: 2, path: "m/49'/1'/0'/0/2"},
+	}
+	for _, test := range tests {
+		path, err := test.scheme.Path(network, test.account, false, test.index)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if path != test.path {
+			t.Errorf("expected %s, got %s", test.path, path)
+		}
+	}
+	if _, err := XverseOrdinals.Path(network, 0, false, 1); !errors.Is(err, ErrInvalidPath) {
+		t.Errorf("expected ErrInvalidPath, got %v", err)
+	}
+	for _, path := range []string{"86'/0'", "m/x", "m/2147483648"} {
+		if _, err := ParsePath(path); !errors.Is(err, ErrInvalidPath) {
+			t.Errorf("%s: expected ErrInvalidPath, got %v", path, err)
+		}
+	}
+}
+
+func TestResolveTxInputs(t *testing.T) {
+	network := &chaincfg.TestNet3Params
+	w, err := NewWallet(testMnemonic, "", network)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ordinals, err := w.Address(XverseOrdinals, 0, false, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	payment, err := w.Address(XversePayment, 0, false, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ins := []*brc20.TxInput{
+		{
+			TxId:           "25b9d08a26c8d47795301dd47a861cff0459d14f27fbd41cffaca17d9aa20f87",
+			VOut:           uint32(0),
+			Amount:         int64(546),
+			Address:        ordinals.Address,
+			DerivationPath: ordinals.Path,
+		},
+		{
+			TxId:           "25b9d08a26c8d47795301dd47a861cff0459d14f27fbd41cffaca17d9aa20f87",
+			VOut:           uint32(1),
+			Amount:         int64(100000),
+			Address:        payment.Address,
+			DerivationPath: payment.Path,
+		},
+	}
+	if err = w.ResolveTxInputs(ins); err != nil {
+		t.Fatal(err)
+	}
+	outs := []*brc20.TxOutput{
+		{Address: "tb1pklh8lqax5l7m2ycypptv2emc4gata2dy28svnwcp9u32wlkenvsspcvhsr", Amount: 546},
+		{Address: payment.Address, Amount: 99000},
+	}
+	if _, err = brc20.Transfer(ins, outs, network); err != nil {
+		t.Fatal(err)
+	}
+
+	// a path the address does not pay to
+	ins[0].PrivateKey = ""
+	ins[0].DerivationPath = "m/86'/1'/0'/0/1"
+	if err = w.ResolveTxInputs(ins); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = brc20.Transfer(ins, outs, network); !errors.Is(err, brc20.ErrKeyMismatch) {
+		t.Fatalf("expected ErrKeyMismatch, got %v", err)
+	}
+}
+
+func TestNewWalletFromExtendedKey(t *testing.T) {
+	if _, err := NewWallet(testMnemonic[:len(testMnemonic)-1], "", &chaincfg.MainNetParams); !errors.Is(err, ErrInvalidMnemonic) {
+		t.Errorf("expected ErrInvalidMnemonic, got %v", err)
+	}
+	w, err := NewWalletFromExtendedKey("xprv9s21ZrQH143K3GJpoapnV8SFfukcVBSfeCficPSGfubmSFDxo1kuHnLisriDvSnRRuL2Qrg5ggqHKNVpxR86QEC8w35uxmGoggxtQTPvfUu", &chaincfg.MainNetParams)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := w.Address(BIP84, 0, false, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if key.Address != "bc1qcr8te4kr609gcawutmrza0j4xv80jy8z306fyu" {
+		t.Errorf("unexpected address %s", key.Address)
+	}
+	if _, err = NewWalletFromExtendedKey("xprv9s21ZrQH143K3GJpoapnV8SFfukcVBSfeCficPSGfubmSFDxo1kuHnLisriDvSnRRuL2Qrg5ggqHKNVpxR86QEC8w35uxmGoggxtQTPvfUu", &chaincfg.TestNet3Params); !errors.Is(err, ErrInvalidExtendedKey) {
+		t.Errorf("expected ErrInvalidExtendedKey, got %v", err)
+	}
+}